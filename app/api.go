@@ -8,6 +8,19 @@ import (
 )
 
 func API() {
+	// clones apply migrations too, reloads start clones of a new binary
+	// which may have new migrations, they run under a lock so clones
+	// don't race over them
+	requireMigrations()
+	// test database gets filled with seeds to get up and running fast
+	if !IsChild() && g.CFG().Debug {
+		seedDatabase(false)
+	}
+	requireTranslator()
+	requireLogger()
+	requireMedia()
+	requireCron()
+
 	// Print Info
 	info()
 
@@ -20,5 +33,8 @@ func API() {
 
 	runCronJobs()
 
+	// Hot reload of configs
+	watchConfigs()
+
 	RunClonesAndServer(app)
 }
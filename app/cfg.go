@@ -7,6 +7,8 @@ import (
 	"os"
 	"path/filepath"
 	"runtime"
+	"strings"
+	"sync"
 
 	"github.com/robfig/cron/v3"
 	migrate "github.com/rubenv/sql-migrate"
@@ -20,12 +22,17 @@ import (
 	db "service/pkg/database"
 	"service/pkg/logging"
 	media_manager "service/pkg/media"
+	"service/pkg/migrations"
 	"service/pkg/translator"
 )
 
 var (
 	cfg       = &iconfig.Config{}
-	languages = []language.Tag{language.English, language.Persian}
+	loadedCfg = iconfig.Config{}
+	cfgFields = []config.Field{}
+	// Overrides passed by `-set` flag
+	cfgOverrides = []string{}
+	languages    = []language.Tag{language.English, language.Persian}
 )
 
 // Set Project PWD
@@ -43,20 +50,52 @@ func setPwd() {
 	os.Chdir(cfg.PWD)
 }
 
+// Prefix of environment variables which override configs, based on project name
+func envPrefix() string {
+	return strings.ToUpper(strings.TrimSpace(g.Name))
+}
+
+// Sources of configs in order, latter ones override former ones
+func configSources(pwd string) []config.Source {
+	sources := []config.Source{
+		// Loads default config, you just have to hard code it
+		config.BytesSource("defaults", build.Config, ".yaml"),
+		config.FileSource(pwd+"/env.yaml", false),
+		config.FileSource(pwd+"/env.yml", false),
+		config.FileSource(pwd+"/env.json", false),
+		config.FileSource(pwd+"/env.toml", false),
+		// Environment variables override files, like APP_SECRET_KEY or APP_SECRET_KEY_FILE
+		config.DotenvSource(pwd+"/.env", envPrefix(), false),
+		config.EnvSource(envPrefix()),
+	}
+	// `-set path=value` flags of commands override everything
+	if len(cfgOverrides) > 0 {
+		sources = append(sources, config.OverridesSource("flags", cfgOverrides))
+	}
+	return sources
+}
+
+// Loads all config sources into a fresh config and validates it, also
+// returns every config with the source it came from
+func loadConfigs(pwd string) (*iconfig.Config, []config.Field, error) {
+	loaded := &iconfig.Config{PWD: pwd}
+	fields, err := config.Load(loaded, configSources(pwd)...)
+	if err != nil {
+		return nil, nil, err
+	}
+	return loaded, fields, loaded.Validate()
+}
+
 // Initialization for config files in configs folder
 func initializeConfigs() {
-	// Loads default config, you just have to hard code it
-	if err := config.ParseYamlBytes(build.Config, cfg); err != nil {
+	loaded, fields, err := loadConfigs(cfg.PWD)
+	if err != nil {
 		log.Fatalln(err)
 	}
-
-	if err1, err2 := config.Parse(cfg.PWD+"/env.yaml", cfg, false), config.Parse(cfg.PWD+"/env.yml", cfg, false); err1 != nil || err2 != nil {
-		if err1 != nil {
-			log.Fatalln(err1)
-		} else if err2 != nil {
-			log.Fatalln(err2)
-		}
-	}
+	// keep configs as they were loaded to detect changes on reload
+	loadedCfg = *loaded
+	cfgFields = fields
+	cfg = loaded
 
 	if cfg.ClonesCount < 0 {
 		cfg.ClonesCount = runtime.GOMAXPROCS(0)
@@ -74,7 +113,7 @@ func initializeConfigs() {
 			break
 		}
 	}
-	g.CFG = cfg
+	g.Configs.Swap(cfg)
 }
 
 // Translator initialization
@@ -107,7 +146,7 @@ func initialDBs() {
 	}
 
 	var ok bool = false
-	if !g.CFG.Debug {
+	if !g.CFG().Debug {
 		_, ok = g.AllSQLCons["main"]
 		if !ok {
 			log.Fatalln(errors.New("'main' db is not defined (required)"))
@@ -120,20 +159,49 @@ func initialDBs() {
 	}
 }
 
+// Name of the default database, `test` in debug mode and `main` otherwise
+func mainOrTest() string {
+	if g.CFG().Debug {
+		return "test"
+	}
+	return "main"
+}
+
+// Embedded migrations of the default database type
+func appMigrations() *migrations.Migrations {
+	database := g.CFG().Gateway.Databases[mainOrTest()]
+	m, err := migrations.New(build.Migrations, "migrations", database.Type)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	// sqlite has no lock of its own, so a file next to it gets locked
+	m.SetLockFile(database.DbName + ".migrate.lock")
+	return m
+}
+
+// Applies pending migrations if auto_migrate is true, otherwise just
+// makes sure there is no pending migration
 func migrateLatestChanges() {
 	db, err := g.DB()
 	if err != nil {
 		panic(err)
 	}
-	mainOrTest := "test"
-	if !g.CFG.Debug {
-		mainOrTest = "main"
-	}
-	migrations := &migrate.FileMigrationSource{
-		Dir: fmt.Sprintf("migrations/%s/", mainOrTest),
+	defer db.Close()
+
+	m := appMigrations()
+	if !g.CFG().AutoMigrate {
+		if err := m.Verify(db); err != nil {
+			log.Fatalln(err)
+		}
+		if n, err := m.Pending(db); err != nil {
+			log.Fatalln(err)
+		} else if n > 0 {
+			log.Fatalf("there are %d pending migrations and auto_migrate is false, apply them with `migrate up` command\n", n)
+		}
+		return
 	}
 
-	n, err := migrate.Exec(db, g.CFG.Gateway.Databases[mainOrTest].Type, migrations, migrate.Up)
+	n, err := m.Exec(db, migrate.Up, 0)
 	if err != nil {
 		log.Fatalln(err)
 	}
@@ -153,14 +221,36 @@ func initialCron() {
 	g.Cron.Start()
 }
 
-// Server initialization
-func init() {
-	setPwd()
-	initializeConfigs()
-	initialDBs()
-	migrateLatestChanges()
-	initialTranslator()
-	initialLogger()
-	initialMedia()
-	initialCron()
+// Returns a function which runs `fn` only the first time it gets called
+func once(fn func()) func() {
+	o := &sync.Once{}
+	return func() {
+		o.Do(fn)
+	}
 }
+
+// Every command initializes just what it needs, lazily
+var (
+	requireConfigs = once(func() {
+		setPwd()
+		initializeConfigs()
+	})
+	requireDBs = once(func() {
+		requireConfigs()
+		initialDBs()
+	})
+	requireMigrations = once(func() {
+		requireDBs()
+		migrateLatestChanges()
+	})
+	requireTranslator = once(initialTranslator)
+	requireLogger     = once(func() {
+		requireConfigs()
+		initialLogger()
+	})
+	requireMedia = once(func() {
+		requireConfigs()
+		initialMedia()
+	})
+	requireCron = once(initialCron)
+)
@@ -1,12 +1,11 @@
 package app
 
 import (
-	"errors"
-	"fmt"
 	"os"
-	"os/exec"
 	"runtime"
 	g "service/global"
+	"strconv"
+	"sync"
 
 	"github.com/kataras/iris/v12"
 )
@@ -15,6 +14,7 @@ const (
 	envCloneChildKey    = "IRIS_CLONE_CHILD"
 	envCloneChildVal    = "1"
 	envCloneChildNumber = "IRIS_CLONE_CHILD_NUM"
+	envCloneReadyFd     = "IRIS_CLONE_READY_FD"
 )
 
 func IsChild() bool {
@@ -25,81 +25,47 @@ func GetChildNumber() string {
 	return os.Getenv(envCloneChildNumber)
 }
 
+var readyOnce sync.Once
+
+// Tells master that this clone is serving, so master can drain
+// the old generation of clones on reload
+func notifyReady() {
+	readyOnce.Do(func() {
+		fd, err := strconv.Atoi(os.Getenv(envCloneReadyFd))
+		if err != nil {
+			return
+		}
+		pipe := os.NewFile(uintptr(fd), "ready")
+		pipe.Write([]byte{1})
+		pipe.Close()
+	})
+}
+
 func RunClonesAndServer(app *iris.Application) {
 	if IsChild() {
 		// use 1 cpu core per child process
 		runtime.GOMAXPROCS(1)
-		app.Listen(g.CFG.Gateway.IP+":"+g.CFG.Gateway.Port, iris.WithSocketSharding)
+		serve(app, true)
 		return
 	}
 
-	type child struct {
-		pid int
-		err error
-	}
-
 	// create variables
-	max := runtime.GOMAXPROCS(g.CFG.ClonesCount)
+	max := runtime.GOMAXPROCS(g.CFG().ClonesCount)
 
-	if g.CFG.ClonesCount == 0 {
+	if g.CFG().ClonesCount == 0 {
 		max = 0
-	} else if g.CFG.ClonesCount > max {
-		max = g.CFG.ClonesCount
-	} else if g.CFG.ClonesCount < max && g.CFG.ClonesCount != -1 {
-		max = g.CFG.ClonesCount
-	}
-	childs := make(map[int]*exec.Cmd)
-	channel := make(chan child, max)
-
-	channelShutdownInfo := make(chan any, 1)
-	// kill child procs when master exits
-	defer func() {
-		channelShutdownInfo <- 1
-		for _, proc := range childs {
-			if err := proc.Process.Kill(); err != nil {
-				if !errors.Is(err, os.ErrProcessDone) {
-					g.Logger.Error(fmt.Sprintf("clone: failed to kill child: %v\n", err), nil, RunClonesAndServer)
-				}
-			}
-		}
-	}()
-
-	// launch child procs
-	for i := 0; i < max; i++ {
-		cmd := exec.Command(os.Args[0], os.Args[1:]...) //nolint:gosec // It's fine to launch the same process again
-		cmd.Stdout = os.Stdout
-		cmd.Stderr = os.Stderr
-
-		// add fiber clone child flag into child proc env
-		cmd.Env = append(os.Environ(),
-			fmt.Sprintf("%s=%s", envCloneChildKey, envCloneChildVal),
-			fmt.Sprintf("%s=%d", envCloneChildNumber, i+1),
-		)
-		if err := cmd.Start(); err != nil {
-			g.Logger.Error("failed to start a child clone process", nil, RunClonesAndServer)
-			return
-		}
-
-		// store child process
-		pid := cmd.Process.Pid
-		childs[pid] = cmd
-
-		// notify master if child crashes
-		go func() {
-			channel <- child{pid, cmd.Wait()}
-		}()
+	} else if g.CFG().ClonesCount > max {
+		max = g.CFG().ClonesCount
+	} else if g.CFG().ClonesCount < max && g.CFG().ClonesCount != -1 {
+		max = g.CFG().ClonesCount
 	}
 
 	// Run App
 	if max > 0 {
-		// return error if child crashes
-		select {
-		case crashedProcess := <-channel:
-			g.Logger.Error(fmt.Sprintf("error: process with %d id crashed", crashedProcess.pid), nil, RunClonesAndServer)
-		case <-channelShutdownInfo:
-			return
-		}
+		// master doesn't serve and just watches the clones
+		files, fds := inheritListeners()
+		newSupervisor(g.CFG().Supervisor, max, files, fds).run(max)
 	} else {
-		app.Listen(g.CFG.Gateway.IP + ":" + g.CFG.Gateway.Port)
+		serve(app, false)
 	}
 }
@@ -0,0 +1,167 @@
+package app
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	g "service/global"
+	"service/routes"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/context"
+)
+
+// A subcommand of the service binary, like `migrate up`
+type command struct {
+	name        string
+	usage       string
+	description string
+	run         func(args []string)
+	subcommands []*command
+}
+
+func rootCommands() []*command {
+	return []*command{
+		{name: "serve", usage: "serve [-set path=value]", description: "Runs the server (default command)", run: serveCommand},
+		migrateCommand(),
+		{name: "seed", usage: "seed [-force]", description: "Upserts seeds of the default database", run: seedCommand},
+		{name: "createsuperuser", usage: "createsuperuser [-phone ...] [-password ...]", description: "Creates an active superuser", run: createSuperuserCommand},
+		{name: "routes", usage: "routes", description: "Prints the route table", run: routesCommand},
+		{name: "generate", usage: "generate", description: "Generates a secret key and saves it into the env file", run: generateCommand},
+		{
+			name:        "config",
+			description: "Inspects configs",
+			subcommands: []*command{
+				{name: "print", usage: "config print [-set path=value]", description: "Prints effective configs and where each one came from", run: configPrintCommand},
+				{name: "validate", usage: "config validate [-set path=value]", description: "Validates configs and reports all problems", run: configValidateCommand},
+			},
+		},
+		{name: "version", usage: "version", description: "Prints the version", run: func(args []string) {
+			fmt.Println(strings.TrimSpace(g.Version))
+		}},
+	}
+}
+
+// Runs the command which `args` point to, `serve` if there is no command
+func Execute(args []string) {
+	if len(args) == 0 || (strings.HasPrefix(args[0], "-") && args[0] != "-h" && args[0] != "--help") {
+		args = append([]string{"serve"}, args...)
+	}
+	execute(rootCommands(), args, "")
+}
+
+func execute(commands []*command, args []string, parent string) {
+	if len(args) == 0 || args[0] == "help" || args[0] == "-h" || args[0] == "--help" {
+		printUsage(commands, parent)
+		return
+	}
+
+	for _, c := range commands {
+		if c.name != args[0] {
+			continue
+		}
+		if len(c.subcommands) > 0 {
+			execute(c.subcommands, args[1:], strings.TrimSpace(parent+" "+c.name))
+		} else {
+			c.run(args[1:])
+		}
+		return
+	}
+
+	printUsage(commands, parent)
+	log.Fatalf("unknown command `%s`\n", strings.TrimSpace(parent+" "+args[0]))
+}
+
+func printUsage(commands []*command, parent string) {
+	fmt.Printf("Usage: %s <command> [flags]\n\nCommands:\n", strings.TrimSpace(g.Name))
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	for _, c := range commands {
+		usage := c.usage
+		if usage == "" {
+			usage = strings.TrimSpace(parent+" "+c.name) + " <command>"
+		}
+		fmt.Fprintf(w, "  %s\t%s\n", usage, c.description)
+	}
+	w.Flush()
+}
+
+// Returns flags of a command, every command accepts `-set path=value`
+// (repeatable) which overrides configs from all other sources
+func newFlagSet(name string) *flag.FlagSet {
+	flags := flag.NewFlagSet(name, flag.ExitOnError)
+	flags.Func("set", "overrides a config, like `gateway.port=8080`", func(override string) error {
+		cfgOverrides = append(cfgOverrides, override)
+		return nil
+	})
+	return flags
+}
+
+func serveCommand(args []string) {
+	newFlagSet("serve").Parse(args)
+	API()
+}
+
+func routesCommand(args []string) {
+	newFlagSet("routes").Parse(args)
+	requireConfigs()
+	requireTranslator()
+
+	app := iris.New()
+	routes.HTTP(app)
+
+	all := app.GetRoutes()
+	sort.SliceStable(all, func(i, j int) bool {
+		if all[i].Path != all[j].Path {
+			return all[i].Path < all[j].Path
+		}
+		return all[i].Method < all[j].Method
+	})
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "METHOD\tPATH\tHANDLER")
+	for _, route := range all {
+		// the last handler is the route's own, others are middlewares
+		handler := context.HandlerName(route.Handlers[len(route.Handlers)-1])
+		fmt.Fprintf(w, "%s\t%s\t%s\n", route.Method, route.Path, handler)
+	}
+	w.Flush()
+}
+
+func configPrintCommand(args []string) {
+	newFlagSet("config print").Parse(args)
+	setPwd()
+	_, fields, err := loadConfigs(cfg.PWD)
+	if fields == nil {
+		log.Fatalln(err)
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "CONFIG\tVALUE\tSOURCE")
+	for _, field := range fields {
+		value := field.Value
+		if field.Secret && value != "" {
+			value = "******"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", field.Path, value, field.Source)
+	}
+	w.Flush()
+
+	if err != nil {
+		fmt.Fprintln(os.Stderr)
+		log.Fatalln(err)
+	}
+}
+
+func configValidateCommand(args []string) {
+	newFlagSet("config validate").Parse(args)
+	setPwd()
+	if _, _, err := loadConfigs(cfg.PWD); err != nil {
+		log.Fatalln(err)
+	}
+	fmt.Println("configs are valid")
+}
@@ -1,11 +1,14 @@
 package app
 
 import (
+	"context"
 	"fmt"
 	"log"
 	"runtime"
+	"time"
 
 	g "service/global"
+	"service/models"
 	"service/pkg/colors"
 )
 
@@ -15,6 +18,34 @@ func runCronJobs() {
 	//
 	// Example:
 	// g.Cron.AddJob("* * * * *", some_function)
+
+	// clones share the database, so only the main process purges
+	if !IsChild() {
+		g.Cron.AddFunc("0 0 3 * * *", purgeDeletedUsers)
+	}
+}
+
+// Deletes users for real which got deleted more than soft_delete_retention days ago
+func purgeDeletedUsers() {
+	retention := g.CFG().SoftDeleteRetention
+	if retention == 0 {
+		return
+	}
+	db, err := g.DB()
+	if err != nil {
+		g.Logger.Error(fmt.Sprintf("purging deleted users failed: %v", err), nil, purgeDeletedUsers)
+		return
+	}
+	defer db.Close()
+
+	count, err := models.Users(db).Purge(context.Background(), time.Now().AddDate(0, 0, -int(retention)))
+	if err != nil {
+		g.Logger.Error(fmt.Sprintf("purging deleted users failed: %v", err), nil, purgeDeletedUsers)
+		return
+	}
+	if count > 0 {
+		g.Logger.Info(fmt.Sprintf("%d deleted users got purged", count), nil, purgeDeletedUsers)
+	}
 }
 
 func info() {
@@ -25,21 +56,25 @@ func info() {
 	fmt.Println(colors.Cyan, fmt.Sprintf("\n==%sSystem Info%s==%s\n", colors.Yellow, colors.Cyan, colors.Reset))
 	fmt.Printf("Name:\t\t\t%s%s%s\n", colors.Blue, g.Name, colors.Reset)
 	fmt.Printf("Version:\t\t%s%s%s\n", colors.Blue, g.Version, colors.Reset)
-	if g.CFG.ClonesCount != 0 {
+	if g.CFG().ClonesCount != 0 {
 		corsCapacity := runtime.GOMAXPROCS(0)
 		cloneColor := colors.Green
-		if g.CFG.ClonesCount > corsCapacity {
+		if g.CFG().ClonesCount > corsCapacity {
 			cloneColor = colors.Red
 		}
-		fmt.Printf("Clones:\t\t\t%s%d%s (%d cors)\n", cloneColor, g.CFG.ClonesCount, colors.Reset, corsCapacity)
+		fmt.Printf("Clones:\t\t\t%s%d%s (%d cors)\n", cloneColor, g.CFG().ClonesCount, colors.Reset, corsCapacity)
+		fmt.Printf("Supervisor:\t\t%s\n", g.CFG().Supervisor.Mode)
+		if g.CFG().Supervisor.StatusAddress != "" {
+			fmt.Printf("Clones Status:\t\thttp://%s/status\n", g.CFG().Supervisor.StatusAddress)
+		}
 	}
 	mainOrTest := "test"
 	mainOrTestColor := colors.Red + mainOrTest + colors.Reset
-	if !g.CFG.Debug {
+	if !g.CFG().Debug {
 		mainOrTest = "main"
 		mainOrTestColor = colors.Green + mainOrTest + colors.Reset
 	}
-	for name, database := range g.CFG.Gateway.Databases {
+	for name, database := range g.CFG().Gateway.Databases {
 		if name == mainOrTest {
 			if database.Type == "sqlite3" {
 				fmt.Printf("Main Database:\t\t%v, %v (%v)\n", database.Type, database.DbName, mainOrTestColor)
@@ -52,15 +87,27 @@ func info() {
 			break
 		}
 	}
-	if g.CFG.Debug {
-		fmt.Printf("Debug:\t\t\t%s%v%s\n", colors.Red, g.CFG.Debug, colors.Reset)
+	if g.CFG().Debug {
+		fmt.Printf("Debug:\t\t\t%s%v%s\n", colors.Red, g.CFG().Debug, colors.Reset)
 	} else {
-		fmt.Printf("Debug:\t\t\t%s%v%s\n", colors.Green, g.CFG.Debug, colors.Reset)
+		fmt.Printf("Debug:\t\t\t%s%v%s\n", colors.Green, g.CFG().Debug, colors.Reset)
+	}
+	for _, listener := range g.CFG().Gateway.GetListeners() {
+		scheme := "http"
+		if listener.TLS {
+			scheme = "https"
+		}
+		if listener.Network == "unix" {
+			scheme += "+unix"
+		}
+		fmt.Printf("Address (%s):\t%s://%s\n", listener.Name, scheme, listener.Address)
+	}
+	if g.CFG().Gateway.TLS.Enabled && g.CFG().Gateway.TLS.RedirectPort != "" {
+		fmt.Printf("Redirect:\t\thttp://%s:%s\n", g.CFG().Gateway.IP, g.CFG().Gateway.TLS.RedirectPort)
 	}
-	fmt.Printf("Address:\t\thttp://%s:%s\n", g.CFG.Gateway.IP, g.CFG.Gateway.Port)
-	fmt.Printf("Allowed Origins:\t%v\n", g.CFG.AllowOrigins)
-	if g.CFG.AllowHeaders != "" {
-		fmt.Printf("Extra Allowed Headers:\t%v\n", g.CFG.AllowHeaders)
+	fmt.Printf("Allowed Origins:\t%v\n", g.CFG().AllowOrigins)
+	if g.CFG().AllowHeaders != "" {
+		fmt.Printf("Extra Allowed Headers:\t%v\n", g.CFG().AllowHeaders)
 	}
 	fmt.Print(colors.Cyan, "===============\n\n", colors.Reset)
 }
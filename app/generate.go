@@ -0,0 +1,54 @@
+package app
+
+import (
+	"crypto/rand"
+	"fmt"
+	"log"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const secretKeyLetters = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// Prints a new secret key and writes it into env.yml or env.yaml if one exists
+func generateCommand(args []string) {
+	newFlagSet("generate").Parse(args)
+
+	key := make([]byte, 64)
+	for i := range key {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(secretKeyLetters))))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		key[i] = secretKeyLetters[n.Int64()]
+	}
+	fmt.Println(string(key))
+
+	setPwd()
+	for _, name := range []string{"env.yml", "env.yaml"} {
+		path := filepath.Join(cfg.PWD, name)
+		content, err := os.ReadFile(path)
+		if err != nil {
+			continue
+		}
+		if err := os.WriteFile(path, []byte(replaceSecretKey(string(content), string(key))), 0o600); err != nil {
+			log.Fatalln(err)
+		}
+		return
+	}
+}
+
+// Replaces secret_key line of an env file, it gets appended if there is none
+func replaceSecretKey(content string, key string) string {
+	line := fmt.Sprintf("secret_key: %q", key)
+	lines := strings.Split(content, "\n")
+	for i, l := range lines {
+		if strings.HasPrefix(l, "secret_key:") {
+			lines[i] = line
+			return strings.Join(lines, "\n")
+		}
+	}
+	return strings.TrimRight(content, "\n") + "\n" + line + "\n"
+}
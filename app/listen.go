@@ -0,0 +1,232 @@
+package app
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	iconfig "service/config"
+	g "service/global"
+	"service/middlewares"
+	"service/pkg/secure"
+
+	"github.com/kataras/iris/v12"
+	"github.com/kataras/iris/v12/core/host"
+	"github.com/kataras/iris/v12/core/netutil"
+)
+
+const (
+	envCloneListenerFds = "IRIS_CLONE_LISTENER_FDS"
+
+	// 0, 1, 2 are std files and 3 is the ready pipe
+	firstListenerFd = 4
+)
+
+// Serves the app on all gateway listeners and blocks until all of them stop
+//
+// `child` has to be true in clones so that all of them can
+// listen on the same addresses
+func serve(app *iris.Application, child bool) {
+	if err := app.Build(); err != nil {
+		log.Fatalln(err)
+	}
+
+	listeners := g.CFG().Gateway.GetListeners()
+	var tlsConfig *tls.Config = nil
+	for _, cfg := range listeners {
+		if cfg.TLS {
+			tlsConfig = newTLSConfig()
+			break
+		}
+	}
+
+	// http gets redirected to the port of the https listener on the same ip
+	if https, ok := g.CFG().Gateway.HTTPSListener(); ok && g.CFG().Gateway.TLS.RedirectPort != "" {
+		ip, port, err := net.SplitHostPort(https.Address)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		redirectServer := redirectToHTTPS(net.JoinHostPort(ip, g.CFG().Gateway.TLS.RedirectPort), port, child)
+		app.ConfigureHost(func(su *host.Supervisor) {
+			su.RegisterOnShutdown(func() {
+				ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+				defer cancel()
+				redirectServer.Shutdown(ctx)
+			})
+		})
+	}
+
+	errs := make(chan error, len(listeners))
+	for _, cfg := range listeners {
+		listener, err := openListener(cfg, child)
+		if err != nil {
+			log.Fatalln(err)
+		}
+
+		name := cfg.Name
+		server := &http.Server{
+			Addr: listener.Addr().String(),
+			ConnContext: func(ctx context.Context, _ net.Conn) context.Context {
+				return middlewares.WithListener(ctx, name)
+			},
+		}
+		if cfg.TLS {
+			server.TLSConfig = tlsConfig
+			listener = tls.NewListener(listener, tlsConfig)
+		}
+
+		su := app.NewHost(server)
+		go func() {
+			errs <- su.Serve(listener)
+		}()
+	}
+
+	if child {
+		notifyReady()
+	}
+
+	for range listeners {
+		if err := <-errs; err != nil && !errors.Is(err, http.ErrServerClosed) {
+			g.Logger.Error(fmt.Sprintf("listener stopped: %v", err), nil, serve)
+		}
+	}
+}
+
+// Creates tls configs based on gateway.tls
+func newTLSConfig() *tls.Config {
+	cfg := g.CFG().Gateway.TLS
+	tlsConfig, err := secure.New(&secure.Option{
+		CertFile:       cfg.CertFile,
+		KeyFile:        cfg.KeyFile,
+		MinVersion:     cfg.MinVersion,
+		CipherSuites:   cfg.CipherSuites,
+		ClientCAFile:   cfg.ClientCAFile,
+		ClientAuth:     cfg.ClientAuth,
+		ReloadInterval: cfg.ReloadInterval,
+	}, func(err error) {
+		g.Logger.Error(fmt.Sprintf("tls: failed to reload certificates: %v", err), nil, serve)
+	})
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return tlsConfig
+}
+
+// Opens passed listener
+//
+// Tcp listeners get shared between clones with socket sharding and
+// unix listeners get inherited from master
+func openListener(cfg iconfig.Listener, child bool) (net.Listener, error) {
+	switch cfg.Network {
+	case "", "tcp":
+		return netutil.TCP(cfg.Address, child)
+	case "unix":
+		if !child {
+			return openUnix(cfg)
+		}
+		for _, pair := range strings.Split(os.Getenv(envCloneListenerFds), ",") {
+			name, fd, _ := strings.Cut(pair, "=")
+			if name != cfg.Name {
+				continue
+			}
+			number, err := strconv.Atoi(fd)
+			if err != nil {
+				return nil, err
+			}
+			file := os.NewFile(uintptr(number), cfg.Name)
+			defer file.Close()
+			return net.FileListener(file)
+		}
+		return nil, fmt.Errorf("listener `%s` is not inherited from master", cfg.Name)
+	default:
+		return nil, fmt.Errorf("listener `%s` has unknown network `%s`", cfg.Name, cfg.Network)
+	}
+}
+
+// Listens on a unix socket, removes the socket file if it is
+// left from a previous run and sets its permissions
+func openUnix(cfg iconfig.Listener) (net.Listener, error) {
+	if info, err := os.Stat(cfg.Address); err == nil && info.Mode()&os.ModeSocket != 0 {
+		os.Remove(cfg.Address)
+	}
+
+	listener, err := net.Listen("unix", cfg.Address)
+	if err != nil {
+		return nil, err
+	}
+
+	if cfg.Permissions != "" {
+		mode, err := strconv.ParseUint(cfg.Permissions, 8, 32)
+		if err != nil {
+			listener.Close()
+			return nil, fmt.Errorf("listener `%s` has invalid permissions `%s`", cfg.Name, cfg.Permissions)
+		}
+		if err := os.Chmod(cfg.Address, os.FileMode(mode)); err != nil {
+			listener.Close()
+			return nil, err
+		}
+	}
+
+	return listener, nil
+}
+
+// Opens unix listeners in master so that clones can inherit them
+//
+// Returns files to pass to clones and the env value describing their fds
+func inheritListeners() ([]*os.File, string) {
+	files := []*os.File{}
+	fds := []string{}
+	for _, cfg := range g.CFG().Gateway.GetListeners() {
+		if cfg.Network != "unix" {
+			continue
+		}
+		listener, err := openUnix(cfg)
+		if err != nil {
+			log.Fatalln(err)
+		}
+		unixListener := listener.(*net.UnixListener)
+		unixListener.SetUnlinkOnClose(false)
+		file, err := unixListener.File()
+		if err != nil {
+			log.Fatalln(err)
+		}
+		unixListener.Close()
+		fds = append(fds, fmt.Sprintf("%s=%d", cfg.Name, firstListenerFd+len(files)))
+		files = append(files, file)
+	}
+	return files, strings.Join(fds, ",")
+}
+
+// Starts a http server on `addr` which redirects every request
+// to the same url on https
+func redirectToHTTPS(addr, httpsPort string, sharding bool) *http.Server {
+	server := &http.Server{
+		Addr: addr,
+		Handler: http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			hostname, _, err := net.SplitHostPort(r.Host)
+			if err != nil {
+				hostname = r.Host
+			}
+			if httpsPort != "443" {
+				hostname = net.JoinHostPort(hostname, httpsPort)
+			}
+			http.Redirect(w, r, "https://"+hostname+r.URL.RequestURI(), http.StatusMovedPermanently)
+		}),
+	}
+
+	listener, err := netutil.TCP(addr, sharding)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	go server.Serve(listener)
+
+	return server
+}
@@ -0,0 +1,168 @@
+package app
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"text/tabwriter"
+
+	g "service/global"
+	pkgMigrations "service/pkg/migrations"
+
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// Directory which `migrate new` creates migrations in, one per database type
+const migrationsDir = "build/migrations"
+
+// Template of new migrations
+const migrationTemplate = "-- +migrate Up\n\n-- +migrate Down\n"
+
+var migrationNumber = regexp.MustCompile(`^(\d+)-`)
+
+func migrateCommand() *command {
+	return &command{
+		name:        "migrate",
+		description: "Manages migrations of the default database",
+		subcommands: []*command{
+			{name: "up", usage: "migrate up [-limit n]", description: "Applies pending migrations", run: migrateUpCommand},
+			{name: "down", usage: "migrate down [-limit n]", description: "Rolls back applied migrations (1 by default)", run: migrateDownCommand},
+			{name: "status", usage: "migrate status", description: "Prints migrations and when they got applied", run: migrateStatusCommand},
+			{name: "redo", usage: "migrate redo", description: "Rolls back the last migration and applies it again", run: migrateRedoCommand},
+			{name: "new", usage: "migrate new <name>", description: "Creates an empty migration", run: migrateNewCommand},
+		},
+	}
+}
+
+// Runs migrations in `direction`, at most `limit` of them (0 => all)
+func execMigrations(direction migrate.MigrationDirection, limit int) int {
+	requireDBs()
+	db, err := g.DB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+
+	n, err := appMigrations().Exec(db, direction, limit)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	return n
+}
+
+func migrateUpCommand(args []string) {
+	flags := newFlagSet("migrate up")
+	limit := flags.Int("limit", 0, "max count of migrations to apply, 0 => all")
+	flags.Parse(args)
+
+	fmt.Printf("Applied %d migrations\n", execMigrations(migrate.Up, *limit))
+}
+
+func migrateDownCommand(args []string) {
+	flags := newFlagSet("migrate down")
+	limit := flags.Int("limit", 1, "max count of migrations to roll back, 0 => all")
+	flags.Parse(args)
+
+	fmt.Printf("Rolled back %d migrations\n", execMigrations(migrate.Down, *limit))
+}
+
+func migrateRedoCommand(args []string) {
+	newFlagSet("migrate redo").Parse(args)
+
+	if execMigrations(migrate.Down, 1) == 0 {
+		fmt.Println("Nothing to redo")
+		return
+	}
+	execMigrations(migrate.Up, 1)
+	fmt.Println("Reapplied the last migration")
+}
+
+func migrateStatusCommand(args []string) {
+	newFlagSet("migrate status").Parse(args)
+	requireDBs()
+	db, err := g.DB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+
+	m := appMigrations()
+	migrations, err := m.Source().FindMigrations()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	records, err := m.Applied(db)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	applied := make(map[string]string, len(records))
+	for _, record := range records {
+		applied[record.Id] = record.AppliedAt.Format("2006-01-02 15:04:05")
+	}
+	edited := map[string]bool{}
+	if err := m.Verify(db); err != nil {
+		checksumErr, ok := err.(*pkgMigrations.ChecksumError)
+		if !ok {
+			log.Fatalln(err)
+		}
+		for _, id := range checksumErr.Ids {
+			edited[id] = true
+		}
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "MIGRATION\tAPPLIED AT")
+	for _, migration := range migrations {
+		appliedAt, ok := applied[migration.Id]
+		if !ok {
+			appliedAt = "pending"
+		} else if edited[migration.Id] {
+			appliedAt += " (edited after being applied)"
+		}
+		fmt.Fprintf(w, "%s\t%s\n", migration.Id, appliedAt)
+	}
+	w.Flush()
+}
+
+func migrateNewCommand(args []string) {
+	flags := newFlagSet("migrate new")
+	flags.Parse(args)
+	if flags.NArg() != 1 {
+		log.Fatalln("usage: migrate new <name>")
+	}
+	name := strings.ToLower(strings.Join(strings.Fields(flags.Arg(0)), "-"))
+
+	setPwd()
+	dirs, err := os.ReadDir(migrationsDir)
+	if err != nil {
+		log.Fatalln(err)
+	}
+
+	next := 1
+	for _, dir := range dirs {
+		entries, err := os.ReadDir(filepath.Join(migrationsDir, dir.Name()))
+		if err != nil {
+			log.Fatalln(err)
+		}
+		for _, entry := range entries {
+			if match := migrationNumber.FindStringSubmatch(entry.Name()); match != nil {
+				if number, _ := strconv.Atoi(match[1]); number >= next {
+					next = number + 1
+				}
+			}
+		}
+	}
+
+	// every database type needs its own version of the migration
+	for _, dir := range dirs {
+		path := filepath.Join(migrationsDir, dir.Name(), fmt.Sprintf("%03d-%s.sql", next, name))
+		if err := os.WriteFile(path, []byte(migrationTemplate), 0644); err != nil {
+			log.Fatalln(err)
+		}
+		fmt.Printf("Created %s\n", path)
+	}
+}
@@ -0,0 +1,55 @@
+package app
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	g "service/global"
+	"service/pkg/config"
+)
+
+// Watches env files and swaps reloadable configs when they change
+//
+// In clone mode master reloads a whole generation on SIGHUP, so clones
+// only watch the files
+func watchConfigs() {
+	if !IsChild() && g.CFG().ClonesCount != 0 {
+		return
+	}
+
+	signals := configReloadSignals
+	if IsChild() {
+		signals = nil
+	}
+
+	pwd := g.CFG().PWD
+	interval := time.Duration(g.CFG().ReloadInterval) * time.Second
+	paths := []string{pwd + "/env.yaml", pwd + "/env.yml", pwd + "/env.json", pwd + "/env.toml", pwd + "/.env"}
+	go config.Watch(paths, interval, signals, reloadConfigs)
+}
+
+// Loads configs again and applies the reloadable changes, configs stay
+// untouched if new ones are invalid
+func reloadConfigs() {
+	fresh, _, err := loadConfigs(g.CFG().PWD)
+	if err != nil {
+		g.Logger.Error(fmt.Sprintf("config: reload failed, keeping current configs:\n%v", err), nil, reloadConfigs)
+		return
+	}
+
+	reloadable, restart := config.Changes(&loadedCfg, fresh)
+	if len(restart) > 0 {
+		g.Logger.Warning(fmt.Sprintf("config: changes of %s require a restart", strings.Join(restart, ", ")), nil, reloadConfigs)
+	}
+	if len(reloadable) == 0 {
+		return
+	}
+
+	current := *g.CFG()
+	config.CopyReloadable(&current, fresh)
+	config.CopyReloadable(&loadedCfg, fresh)
+	g.Configs.Swap(&current)
+
+	g.Logger.Info(fmt.Sprintf("config: reloaded %s", strings.Join(reloadable, ", ")), nil, reloadConfigs)
+}
@@ -0,0 +1,52 @@
+package app
+
+import (
+	"context"
+	"fmt"
+	"log"
+
+	"service/build"
+	g "service/global"
+	"service/models"
+	"service/pkg/repositories"
+	"service/pkg/seeds"
+)
+
+// Seeds of the default database with models of their tables
+func appSeeds() *seeds.Seeds {
+	s, err := seeds.New(build.Seeds, "seeds", mainOrTest())
+	if err != nil {
+		log.Fatalln(err)
+	}
+	s.Register(models.UserName, func() repositories.QueryGenerator {
+		return models.NewUser()
+	})
+	return s
+}
+
+// Upserts seeds of the default database, unchanged seed files get
+// skipped unless `force` is true
+func seedDatabase(force bool) {
+	db, err := g.DB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+
+	n, err := appSeeds().Run(context.Background(), db, force)
+	if err != nil {
+		log.Fatalln(err)
+	}
+	if n > 0 {
+		fmt.Printf("Seeded %d rows into %s database\n", n, mainOrTest())
+	}
+}
+
+func seedCommand(args []string) {
+	flags := newFlagSet("seed")
+	force := flags.Bool("force", false, "seeds files which didn't change since the last run too")
+	flags.Parse(args)
+
+	requireMigrations()
+	seedDatabase(*force)
+}
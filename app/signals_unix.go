@@ -0,0 +1,20 @@
+//go:build !windows
+
+package app
+
+import (
+	"os"
+	"syscall"
+)
+
+var (
+	// Signals which make master start a new generation of clones
+	reloadSignals = []os.Signal{syscall.SIGHUP, syscall.SIGUSR2}
+	// Signal which makes an old clone finish its requests and exit
+	drainSignal os.Signal = syscall.SIGTERM
+	// Signals which make master drain all clones and exit
+	shutdownSignals = []os.Signal{syscall.SIGINT, syscall.SIGTERM}
+)
+
+// Signals which make a single process reload its configs
+var configReloadSignals = []os.Signal{syscall.SIGHUP}
@@ -0,0 +1,17 @@
+//go:build windows
+
+package app
+
+import "os"
+
+var (
+	// Reload is not supported on windows
+	reloadSignals = []os.Signal{}
+	// Windows can't interrupt another process, so old clones just get killed
+	drainSignal os.Signal = os.Kill
+	// Signals which make master stop all clones and exit
+	shutdownSignals = []os.Signal{os.Interrupt}
+)
+
+// Configs only get reloaded on file change on windows
+var configReloadSignals = []os.Signal{}
@@ -0,0 +1,75 @@
+package app
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"strings"
+
+	g "service/global"
+	"service/models"
+
+	"golang.org/x/term"
+)
+
+// Asks for `value` on stdin if it is empty
+func prompt(reader *bufio.Reader, label string, value string, secret bool) string {
+	for strings.TrimSpace(value) == "" {
+		fmt.Printf("%s: ", label)
+		if secret && term.IsTerminal(int(os.Stdin.Fd())) {
+			bytes, err := term.ReadPassword(int(os.Stdin.Fd()))
+			fmt.Println()
+			if err != nil {
+				log.Fatalln(err)
+			}
+			value = string(bytes)
+			continue
+		}
+		line, err := reader.ReadString('\n')
+		if err != nil && line == "" {
+			log.Fatalln(fmt.Errorf("%s is required", strings.ToLower(label)))
+		}
+		value = strings.TrimSpace(line)
+	}
+	return value
+}
+
+func createSuperuserCommand(args []string) {
+	flags := newFlagSet("createsuperuser")
+	phone := flags.String("phone", "", "phone number of the user")
+	password := flags.String("password", "", "password of the user, asked if empty")
+	displayName := flags.String("display-name", "", "display name of the user")
+	email := flags.String("email", "", "email of the user")
+	flags.Parse(args)
+
+	requireMigrations()
+
+	reader := bufio.NewReader(os.Stdin)
+	user := models.NewUser()
+	user.PhoneNumber = prompt(reader, "Phone Number", *phone, false)
+	user.DisplayName = prompt(reader, "Display Name", *displayName, false)
+	user.Email = *email
+	user.Password = prompt(reader, "Password", *password, true)
+	user.IsActive = true
+	user.IsAdmin = true
+	user.IsSuperuser = true
+
+	db, err := g.DB()
+	if err != nil {
+		log.Fatalln(err)
+	}
+	defer db.Close()
+
+	// executors panic on errors
+	defer func() {
+		if err := recover(); err != nil {
+			log.Fatalln(err)
+		}
+	}()
+
+	ctx := context.Background()
+	user.InsertInto().ExecQuery(ctx, db)
+	fmt.Printf("Superuser %s created with %d id\n", user.PhoneNumber, user.Id)
+}
@@ -0,0 +1,516 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/exec"
+	"os/signal"
+	"sort"
+	"sync"
+	"time"
+
+	iconfig "service/config"
+	g "service/global"
+)
+
+const (
+	SupervisorFailFast = "fail_fast"
+	SupervisorRestart  = "restart"
+)
+
+// A clone process which supervisor watches
+type childProcess struct {
+	number     int
+	generation int
+	cmd        *exec.Cmd
+	startedAt  time.Time
+	restarts   int
+	alive      bool
+	draining   bool
+
+	// Closed when the child starts serving
+	ready chan struct{}
+	// Closed when the child exits
+	done chan struct{}
+
+	// Time of crashes inside the restart window
+	crashes []time.Time
+}
+
+type childExit struct {
+	child *childProcess
+	err   error
+}
+
+// Status of a clone reported by status endpoint
+type ChildStatus struct {
+	Number     int       `json:"number"`
+	Generation int       `json:"generation"`
+	Pid        int       `json:"pid"`
+	Alive      bool      `json:"alive"`
+	Draining   bool      `json:"draining"`
+	StartedAt  time.Time `json:"started_at"`
+	Uptime     string    `json:"uptime"`
+	Restarts   int       `json:"restarts"`
+}
+
+type supervisor struct {
+	cfg iconfig.Supervisor
+
+	// Listeners which clones inherit and description of their fds
+	files []*os.File
+	fds   string
+
+	lock       sync.Mutex
+	generation int
+	children   map[int]*childProcess
+	draining   map[*childProcess]bool
+	exits      chan childExit
+	stop       chan any
+	stopOnce   sync.Once
+}
+
+func newSupervisor(cfg iconfig.Supervisor, count int, files []*os.File, fds string) *supervisor {
+	return &supervisor{
+		cfg:        cfg,
+		files:      files,
+		fds:        fds,
+		generation: 1,
+		children:   make(map[int]*childProcess, count),
+		draining:   map[*childProcess]bool{},
+		exits:      make(chan childExit, count),
+		stop:       make(chan any),
+	}
+}
+
+// Launches the child and notifies exits channel when it exits
+func (s *supervisor) start(child *childProcess) error {
+	cmd := exec.Command(os.Args[0], os.Args[1:]...) //nolint:gosec // It's fine to launch the same process again
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+
+	// child writes into this pipe when it starts serving
+	readyReader, readyWriter, err := os.Pipe()
+	if err != nil {
+		return err
+	}
+	cmd.ExtraFiles = append([]*os.File{readyWriter}, s.files...)
+
+	// add clone child flag into child proc env
+	cmd.Env = append(os.Environ(),
+		fmt.Sprintf("%s=%s", envCloneChildKey, envCloneChildVal),
+		fmt.Sprintf("%s=%d", envCloneChildNumber, child.number),
+		fmt.Sprintf("%s=%d", envCloneReadyFd, 3),
+		fmt.Sprintf("%s=%s", envCloneListenerFds, s.fds),
+	)
+	err = cmd.Start()
+	readyWriter.Close()
+	if err != nil {
+		readyReader.Close()
+		return err
+	}
+
+	ready, done := make(chan struct{}), make(chan struct{})
+	s.lock.Lock()
+	child.cmd = cmd
+	child.startedAt = time.Now()
+	child.alive = true
+	child.ready = ready
+	child.done = done
+	s.lock.Unlock()
+
+	go func() {
+		defer readyReader.Close()
+		if _, err := readyReader.Read(make([]byte, 1)); err == nil {
+			close(ready)
+		}
+	}()
+
+	// notify master if child crashes
+	go func() {
+		err := cmd.Wait()
+		s.lock.Lock()
+		child.alive = false
+		s.lock.Unlock()
+		close(done)
+		s.exits <- childExit{child, err}
+	}()
+
+	return nil
+}
+
+// Closes stop channel, so waits of supervisor end and a reload aborts
+func (s *supervisor) close() {
+	s.stopOnce.Do(func() {
+		close(s.stop)
+	})
+}
+
+// Reports if master got a shutdown signal or supervisor gave up
+func (s *supervisor) stopping() bool {
+	select {
+	case <-s.stop:
+		return true
+	default:
+		return false
+	}
+}
+
+// Reports if child belongs to the serving generation
+func (s *supervisor) isCurrent(child *childProcess) bool {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.children[child.number] == child
+}
+
+// Records a crash and reports if child is still allowed to get restarted
+func (s *supervisor) allowRestart(child *childProcess) bool {
+	now := time.Now()
+	window := time.Duration(s.cfg.RestartWindow) * time.Second
+
+	crashes := []time.Time{}
+	for _, crash := range child.crashes {
+		if now.Sub(crash) < window {
+			crashes = append(crashes, crash)
+		}
+	}
+	child.crashes = append(crashes, now)
+
+	return len(child.crashes) <= s.cfg.MaxRestarts
+}
+
+// Returns how long supervisor should wait before restarting the child
+func (s *supervisor) backoff(child *childProcess) time.Duration {
+	backoff := time.Duration(s.cfg.MinBackoff) * time.Millisecond
+	maxBackoff := time.Duration(s.cfg.MaxBackoff) * time.Millisecond
+	for i := 1; i < len(child.crashes) && backoff < maxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > maxBackoff {
+		backoff = maxBackoff
+	}
+	return backoff
+}
+
+// Kills passed child if it is still running
+func (s *supervisor) kill(child *childProcess) {
+	if child.cmd == nil || child.cmd.Process == nil {
+		return
+	}
+	if err := child.cmd.Process.Kill(); err != nil {
+		if !errors.Is(err, os.ErrProcessDone) {
+			g.Logger.Error(fmt.Sprintf("clone: failed to kill child: %v\n", err), nil, RunClonesAndServer)
+		}
+	}
+}
+
+// Kills every alive child
+func (s *supervisor) killAll() {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	for _, child := range s.children {
+		s.kill(child)
+	}
+	for child := range s.draining {
+		s.kill(child)
+	}
+}
+
+// Makes children finish their requests and waits for them to exit,
+// children which are still running after drain timeout get killed
+func (s *supervisor) drain(children []*childProcess) {
+	for _, child := range children {
+		if err := child.cmd.Process.Signal(drainSignal); err != nil && !errors.Is(err, os.ErrProcessDone) {
+			s.lock.Lock()
+			s.kill(child)
+			s.lock.Unlock()
+		}
+	}
+
+	timer := time.NewTimer(time.Duration(s.cfg.DrainTimeout) * time.Second)
+	defer timer.Stop()
+	expired := false
+	for _, child := range children {
+		if !expired {
+			select {
+			case <-child.done:
+				continue
+			case <-timer.C:
+				expired = true
+			}
+		}
+		g.Logger.Warning(fmt.Sprintf("clone: clone %d of generation %d didn't drain in %ds, killing it", child.number, child.generation, s.cfg.DrainTimeout), nil, RunClonesAndServer)
+		s.lock.Lock()
+		s.kill(child)
+		s.lock.Unlock()
+	}
+}
+
+// Drains every alive child, used when master gets a shutdown signal
+func (s *supervisor) shutdown() {
+	s.lock.Lock()
+	children := make([]*childProcess, 0, len(s.children)+len(s.draining))
+	for _, child := range s.children {
+		if child.alive {
+			children = append(children, child)
+		}
+	}
+	for child := range s.draining {
+		if child.alive {
+			children = append(children, child)
+		}
+	}
+	s.lock.Unlock()
+
+	g.Logger.Info(fmt.Sprintf("clone: stopping, draining %d clones", len(children)), nil, RunClonesAndServer)
+	s.drain(children)
+}
+
+// Starts a new generation of children, waits for all of them to
+// become ready and then drains the old generation
+//
+// If the new generation doesn't get ready in time, it gets killed
+// and the old generation keeps serving
+func (s *supervisor) reload() {
+	s.lock.Lock()
+	generation := s.generation + 1
+	count := len(s.children)
+	s.lock.Unlock()
+
+	g.Logger.Info(fmt.Sprintf("clone: reloading, starting generation %d", generation), nil, RunClonesAndServer)
+
+	fresh := make([]*childProcess, 0, count)
+	abort := func(reason string) {
+		s.lock.Lock()
+		for _, child := range fresh {
+			s.kill(child)
+		}
+		s.lock.Unlock()
+		g.Logger.Error(fmt.Sprintf("clone: reload to generation %d aborted: %s", generation, reason), nil, RunClonesAndServer)
+	}
+
+	for i := 1; i <= count; i++ {
+		child := &childProcess{number: i, generation: generation}
+		if err := s.start(child); err != nil {
+			abort(err.Error())
+			return
+		}
+		fresh = append(fresh, child)
+	}
+
+	deadline := time.After(time.Duration(s.cfg.ReadyTimeout) * time.Second)
+	for _, child := range fresh {
+		select {
+		case <-child.ready:
+		case <-child.done:
+			abort(fmt.Sprintf("clone %d exited before getting ready", child.number))
+			return
+		case <-deadline:
+			abort(fmt.Sprintf("clone %d didn't get ready in %ds", child.number, s.cfg.ReadyTimeout))
+			return
+		case <-s.stop:
+			abort("master is stopping")
+			return
+		}
+	}
+
+	s.lock.Lock()
+	old := make([]*childProcess, 0, len(s.children))
+	for _, child := range s.children {
+		if child.alive {
+			child.draining = true
+			s.draining[child] = true
+			old = append(old, child)
+		}
+	}
+	s.generation = generation
+	s.children = make(map[int]*childProcess, count)
+	for _, child := range fresh {
+		s.children[child.number] = child
+	}
+	s.lock.Unlock()
+
+	// old generation finishes in-flight requests and exits
+	go s.drain(old)
+
+	g.Logger.Info(fmt.Sprintf("clone: generation %d is serving", generation), nil, RunClonesAndServer)
+}
+
+// Returns status of all children sorted by their generation and number
+func (s *supervisor) Status() []ChildStatus {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	statuses := make([]ChildStatus, 0, len(s.children)+len(s.draining))
+	add := func(child *childProcess) {
+		status := ChildStatus{
+			Number:     child.number,
+			Generation: child.generation,
+			Alive:      child.alive,
+			Draining:   child.draining,
+			StartedAt:  child.startedAt,
+			Restarts:   child.restarts,
+		}
+		if child.cmd != nil && child.cmd.Process != nil {
+			status.Pid = child.cmd.Process.Pid
+		}
+		if child.alive {
+			status.Uptime = time.Since(child.startedAt).Round(time.Second).String()
+		}
+		statuses = append(statuses, status)
+	}
+	for _, child := range s.children {
+		add(child)
+	}
+	for child := range s.draining {
+		add(child)
+	}
+	sort.Slice(statuses, func(i, j int) bool {
+		if statuses[i].Generation != statuses[j].Generation {
+			return statuses[i].Generation < statuses[j].Generation
+		}
+		return statuses[i].Number < statuses[j].Number
+	})
+	return statuses
+}
+
+// Serves children status as json on `status_address` until the returned
+// server gets closed
+func (s *supervisor) serveStatus() *http.Server {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/status", func(w http.ResponseWriter, r *http.Request) {
+		s.lock.Lock()
+		generation := s.generation
+		s.lock.Unlock()
+		w.Header().Set("Content-Type", "application/json")
+		json.NewEncoder(w).Encode(map[string]any{
+			"mode":       s.cfg.Mode,
+			"generation": generation,
+			"children":   s.Status(),
+		})
+	})
+	server := &http.Server{Addr: s.cfg.StatusAddress, Handler: mux}
+	go func() {
+		if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			g.Logger.Error(fmt.Sprintf("clone: status endpoint stopped: %v", err), nil, RunClonesAndServer)
+		}
+	}()
+	return server
+}
+
+// Starts `count` children and watches them until supervisor gives up
+func (s *supervisor) run(count int) {
+	// kill child procs when master exits
+	defer s.killAll()
+
+	// launch child procs
+	for i := 1; i <= count; i++ {
+		child := &childProcess{number: i, generation: s.generation}
+		if err := s.start(child); err != nil {
+			g.Logger.Error("failed to start a child clone process", nil, RunClonesAndServer)
+			return
+		}
+		s.children[i] = child
+	}
+
+	if s.cfg.StatusAddress != "" {
+		defer s.serveStatus().Close()
+	}
+
+	reloads := make(chan os.Signal, 1)
+	if len(reloadSignals) > 0 {
+		signal.Notify(reloads, reloadSignals...)
+		defer signal.Stop(reloads)
+	}
+
+	// children get drained instead of getting orphaned when master stops,
+	// the default behavior comes back so another signal stops master at once
+	shutdowns := make(chan os.Signal, 1)
+	signal.Notify(shutdowns, shutdownSignals...)
+	go func() {
+		<-shutdowns
+		signal.Stop(shutdowns)
+		s.close()
+	}()
+	defer signal.Stop(shutdowns)
+
+	// reloads run in background so crashes and signals get handled while
+	// the new generation gets ready, a running reload aborts on exit
+	reloaded := make(chan struct{}, 1)
+	reloading := false
+	defer func() {
+		s.close()
+		if reloading {
+			<-reloaded
+		}
+	}()
+
+	for {
+		var exit childExit
+		select {
+		case exit = <-s.exits:
+		case <-reloads:
+			if reloading {
+				g.Logger.Warning("clone: a reload is already running, ignoring the signal", nil, RunClonesAndServer)
+				continue
+			}
+			reloading = true
+			go func() {
+				s.reload()
+				reloaded <- struct{}{}
+			}()
+			continue
+		case <-reloaded:
+			reloading = false
+			continue
+		case <-s.stop:
+			s.shutdown()
+			return
+		}
+
+		child := exit.child
+		if !s.isCurrent(child) {
+			// drained old generation or a failed reload
+			s.lock.Lock()
+			delete(s.draining, child)
+			s.lock.Unlock()
+			continue
+		}
+
+		pid := 0
+		if child.cmd != nil && child.cmd.Process != nil {
+			pid = child.cmd.Process.Pid
+		}
+		if s.cfg.Mode != SupervisorRestart {
+			g.Logger.Error(fmt.Sprintf("error: process with %d id crashed", pid), nil, RunClonesAndServer)
+			return
+		}
+
+		s.lock.Lock()
+		allowed := s.allowRestart(child)
+		backoff := s.backoff(child)
+		crashes := len(child.crashes)
+		s.lock.Unlock()
+		if !allowed {
+			g.Logger.Error(fmt.Sprintf("error: clone %d crashed %d times in %ds, giving up", child.number, crashes, s.cfg.RestartWindow), nil, RunClonesAndServer)
+			return
+		}
+
+		g.Logger.Warning(fmt.Sprintf("clone %d with %d id crashed (%v), restarting in %v", child.number, pid, exit.err, backoff), nil, RunClonesAndServer)
+		time.AfterFunc(backoff, func() {
+			// a reload replaced this child in the meantime or master is stopping
+			if !s.isCurrent(child) || s.stopping() {
+				return
+			}
+			s.lock.Lock()
+			child.restarts++
+			s.lock.Unlock()
+			if err := s.start(child); err != nil {
+				s.exits <- childExit{child, err}
+			}
+		})
+	}
+}
@@ -0,0 +1,16 @@
+-- +migrate Up
+CREATE TABLE users (
+    id BIGINT IDENTITY(1,1) PRIMARY KEY,
+    phone_number NVARCHAR(16) NOT NULL UNIQUE,
+    email NVARCHAR(64) NULL,
+    password NVARCHAR(256) NOT NULL,
+    first_name NVARCHAR(128) NULL,
+    last_name NVARCHAR(128) NULL,
+    display_name NVARCHAR(128) NOT NULL,
+    is_active BIT NOT NULL DEFAULT 0,
+    is_admin BIT NOT NULL DEFAULT 0,
+    is_superuser BIT NOT NULL DEFAULT 0,
+    created_at DATETIME2 NOT NULL
+);
+-- +migrate Down
+DROP TABLE users;
@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE tokens (
+    id BIGINT IDENTITY(1,1) PRIMARY KEY,
+    token NVARCHAR(256) NOT NULL,
+    is_refresh_token BIT NOT NULL DEFAULT 0,
+    user_id BIGINT NOT NULL,
+    expires_at DATETIME2 NOT NULL,
+    created_at DATETIME2 NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +migrate Down
+DROP TABLE tokens;
@@ -0,0 +1,4 @@
+-- +migrate Up
+ALTER TABLE users ADD deleted_at DATETIME2 NULL;
+-- +migrate Down
+ALTER TABLE users DROP COLUMN deleted_at;
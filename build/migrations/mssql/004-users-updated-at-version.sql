@@ -0,0 +1,8 @@
+-- +migrate Up
+ALTER TABLE users ADD updated_at DATETIME2 NULL;
+UPDATE users SET updated_at = created_at;
+ALTER TABLE users ADD version BIGINT NOT NULL CONSTRAINT df_users_version DEFAULT 1;
+-- +migrate Down
+ALTER TABLE users DROP CONSTRAINT df_users_version;
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE users DROP COLUMN updated_at;
@@ -0,0 +1,12 @@
+-- +migrate Up
+CREATE TABLE tokens (
+    id BIGINT NOT NULL AUTO_INCREMENT PRIMARY KEY,
+    token VARCHAR(256) NOT NULL,
+    is_refresh_token BOOLEAN NOT NULL DEFAULT FALSE,
+    user_id BIGINT NOT NULL,
+    expires_at DATETIME(6) NOT NULL,
+    created_at DATETIME(6) NOT NULL,
+    FOREIGN KEY (user_id) REFERENCES users(id) ON DELETE CASCADE
+);
+-- +migrate Down
+DROP TABLE tokens;
@@ -0,0 +1,4 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN deleted_at DATETIME NULL;
+-- +migrate Down
+ALTER TABLE users DROP COLUMN deleted_at;
@@ -0,0 +1,7 @@
+-- +migrate Up
+ALTER TABLE users ADD COLUMN updated_at DATETIME NULL;
+UPDATE users SET updated_at = created_at;
+ALTER TABLE users ADD COLUMN version INTEGER NOT NULL DEFAULT 1;
+-- +migrate Down
+ALTER TABLE users DROP COLUMN version;
+ALTER TABLE users DROP COLUMN updated_at;
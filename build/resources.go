@@ -7,3 +7,13 @@ var Translations embed.FS
 
 //go:embed config/config.yaml
 var Config []byte
+
+// Migrations of every database type in its own folder, like migrations/postgres
+//
+//go:embed migrations
+var Migrations embed.FS
+
+// Seeds of every database in its own folder, like seeds/test
+//
+//go:embed seeds
+var Seeds embed.FS
@@ -2,6 +2,8 @@ package config
 
 import db "service/pkg/database"
 
+// Fields with `reload:"+"` tag get updated on hot reload without
+// restart, changes of others only take effect after a restart
 type (
 	Config struct {
 		Logging               Logging      `yaml:"logging"`
@@ -10,18 +12,23 @@ type (
 		Debug                 bool         `yaml:"debug"`
 		Domain                string       `yaml:"domain"`
 		PWD                   string       `yaml:"pwd"`
-		AllowOrigins          string       `yaml:"allow_origins"`
-		AllowHeaders          string       `yaml:"allow_headers"`
-		MaxAge                int          `yaml:"max_age"`
-		Timeout               int64        `yaml:"timeout"`
-		MaxConcurrentRequests int          `yaml:"max_concurrent_requests"`
-		SecretKey             string       `yaml:"secret_key"`
+		AllowOrigins          string       `yaml:"allow_origins" reload:"+"`
+		AllowHeaders          string       `yaml:"allow_headers" reload:"+"`
+		MaxAge                int          `yaml:"max_age" reload:"+"`
+		Timeout               int64        `yaml:"timeout" reload:"+"`
+		MaxConcurrentRequests int          `yaml:"max_concurrent_requests" reload:"+"`
+		SecretKey             string       `yaml:"secret_key" secret:"+"`
 		Media                 string       `yaml:"media"`
+		ReloadInterval        int64        `yaml:"reload_interval"` // Based on Seconds, 0 => env.yaml doesn't get watched
+		AutoMigrate           bool         `yaml:"auto_migrate"`    // false => server refuses to start with pending migrations
+		Supervisor            Supervisor   `yaml:"supervisor"`
 
 		// Based on Days
-		AccessTokenLifePeriod int64 `yaml:"access_token_life_period"`
+		AccessTokenLifePeriod int64 `yaml:"access_token_life_period" reload:"+"`
 		// Based on Months
-		RefreshTokenLifePeriod int64 `yaml:"refresh_token_life_period"`
+		RefreshTokenLifePeriod int64 `yaml:"refresh_token_life_period" reload:"+"`
+		// Based on Days, soft deleted rows get purged after it, 0 => never
+		SoftDeleteRetention int64 `yaml:"soft_delete_retention" reload:"+"`
 	}
 
 	Logging struct {
@@ -32,9 +39,72 @@ type (
 		RotationSize string `yaml:"rotation_size"`
 	}
 
+	Supervisor struct {
+		Mode          string `yaml:"mode"`           // fail_fast or restart
+		MaxRestarts   int    `yaml:"max_restarts"`   // Max restarts of a clone inside RestartWindow
+		RestartWindow int64  `yaml:"restart_window"` // Based on Seconds
+		MinBackoff    int64  `yaml:"min_backoff"`    // Based on Milliseconds
+		MaxBackoff    int64  `yaml:"max_backoff"`    // Based on Milliseconds
+		StatusAddress string `yaml:"status_address"` // Empty => status endpoint is disabled
+		ReadyTimeout  int64  `yaml:"ready_timeout"`  // Based on Seconds
+		DrainTimeout  int64  `yaml:"drain_timeout"`  // Based on Seconds
+	}
+
+	TLS struct {
+		Enabled               bool     `yaml:"enabled"`
+		CertFile              string   `yaml:"cert_file"`
+		KeyFile               string   `yaml:"key_file"`
+		MinVersion            string   `yaml:"min_version"`     // 1.0, 1.1, 1.2 or 1.3
+		CipherSuites          []string `yaml:"cipher_suites"`   // Empty => go defaults
+		ClientCAFile          string   `yaml:"client_ca_file"`  // Empty => no client certificate verification
+		ClientAuth            string   `yaml:"client_auth"`     // none, request, require, verify_if_given or require_and_verify
+		ReloadInterval        int64    `yaml:"reload_interval"` // Based on Seconds, 0 => no hot reload
+		RedirectPort          string   `yaml:"redirect_port"`   // Empty => no http to https redirect
+		HSTSMaxAge            int64    `yaml:"hsts_max_age"`    // Based on Seconds, 0 => no HSTS header
+		HSTSIncludeSubdomains bool     `yaml:"hsts_include_subdomains"`
+		HSTSPreload           bool     `yaml:"hsts_preload"`
+	}
+
+	Listener struct {
+		Name        string `yaml:"name"`
+		Network     string `yaml:"network"`     // tcp or unix
+		Address     string `yaml:"address"`     // ip:port for tcp and file path for unix
+		Permissions string `yaml:"permissions"` // File mode of unix socket, like "0660"
+		TLS         bool   `yaml:"tls"`         // Serves https with gateway.tls configs
+	}
+
 	Microservice struct {
 		Databases map[string]db.Database `yaml:"databases"`
 		IP        string                 `yaml:"ip"`
 		Port      string                 `yaml:"port"`
+		TLS       TLS                    `yaml:"tls"`
+		Listeners []Listener             `yaml:"listeners"`      // Empty => one tcp listener named main on ip:port
+		Admin     string                 `yaml:"admin_listener"` // Listener of admin routes, empty => all listeners
 	}
 )
+
+// Default listener name which gets created on ip:port if no listener is defined
+const MainListener = "main"
+
+// Returns defined listeners or one tcp listener on ip:port if none is defined
+func (m Microservice) GetListeners() []Listener {
+	if len(m.Listeners) != 0 {
+		return m.Listeners
+	}
+	return []Listener{{
+		Name:    MainListener,
+		Network: "tcp",
+		Address: m.IP + ":" + m.Port,
+		TLS:     m.TLS.Enabled,
+	}}
+}
+
+// Returns the first tcp listener which serves https, http gets redirected to it
+func (m Microservice) HTTPSListener() (Listener, bool) {
+	for _, listener := range m.GetListeners() {
+		if listener.TLS && listener.Network == "tcp" {
+			return listener, true
+		}
+	}
+	return Listener{}, false
+}
@@ -0,0 +1,242 @@
+package config
+
+import (
+	"fmt"
+	"net"
+	"sort"
+	"strconv"
+	"strings"
+
+	db "service/pkg/database"
+	"service/pkg/secure"
+
+	"github.com/alecthomas/units"
+	"github.com/xhit/go-str2duration/v2"
+)
+
+// Secret key which is shipped with the project and must be changed in production
+const DefaultSecretKey = "update_me_please"
+
+type (
+	// A problem found in a config, `Path` is the yaml path of the config
+	FieldError struct {
+		Path    string
+		Message string
+	}
+
+	// All problems found in configs
+	ValidationErrors []FieldError
+)
+
+func (e FieldError) Error() string {
+	return fmt.Sprintf("%s: %s", e.Path, e.Message)
+}
+
+func (e ValidationErrors) Error() string {
+	problems := make([]string, 0, len(e))
+	for _, err := range e {
+		problems = append(problems, "  - "+err.Error())
+	}
+	return fmt.Sprintf("config: %d problem(s) found:\n%s", len(e), strings.Join(problems, "\n"))
+}
+
+// Collects problems while validating
+type validator struct {
+	errs ValidationErrors
+}
+
+func (v *validator) add(path string, format string, args ...any) {
+	v.errs = append(v.errs, FieldError{Path: path, Message: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) required(path string, value string) bool {
+	if strings.TrimSpace(value) == "" {
+		v.add(path, "is required")
+		return false
+	}
+	return true
+}
+
+func (v *validator) min(path string, value int64, min int64) {
+	if value < min {
+		v.add(path, "has to be at least %d (got %d)", min, value)
+	}
+}
+
+func (v *validator) duration(path string, value string) {
+	if !v.required(path, value) {
+		return
+	}
+	if _, err := str2duration.ParseDuration(value); err != nil {
+		v.add(path, "`%s` is not a valid duration, like \"24h\" or \"1w\"", value)
+	}
+}
+
+func (v *validator) size(path string, value string) {
+	if !v.required(path, value) {
+		return
+	}
+	if _, err := units.ParseBase2Bytes(value); err != nil {
+		v.add(path, "`%s` is not a valid size, like \"20MB\"", value)
+	}
+}
+
+func (v *validator) port(path string, value string) {
+	if !v.required(path, value) {
+		return
+	}
+	if port, err := strconv.Atoi(value); err != nil || port < 1 || port > 65535 {
+		v.add(path, "`%s` is not a valid port", value)
+	}
+}
+
+func (v *validator) choice(path string, value string, choices ...string) {
+	for _, choice := range choices {
+		if value == choice {
+			return
+		}
+	}
+	v.add(path, "`%s` is not one of %s", value, strings.Join(choices, ", "))
+}
+
+// Validates all configs and returns all problems found
+// at once as ValidationErrors, nil if everything is fine
+func (c *Config) Validate() error {
+	v := &validator{}
+
+	c.validateLogging(v)
+	c.validateGateway(v)
+	c.validateSupervisor(v)
+
+	v.min("clones_count", int64(c.ClonesCount), -1)
+	v.min("max_age", int64(c.MaxAge), 0)
+	v.min("timeout", c.Timeout, 1)
+	v.min("max_concurrent_requests", int64(c.MaxConcurrentRequests), 1)
+	v.min("access_token_life_period", c.AccessTokenLifePeriod, 1)
+	v.min("refresh_token_life_period", c.RefreshTokenLifePeriod, 1)
+	v.min("soft_delete_retention", c.SoftDeleteRetention, 0)
+	v.required("media", c.Media)
+	v.min("reload_interval", c.ReloadInterval, 0)
+	if v.required("secret_key", c.SecretKey) && !c.Debug && c.SecretKey == DefaultSecretKey {
+		v.add("secret_key", "default secret key can't be used when debug is false, generate one with `generate` command")
+	}
+
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return v.errs
+}
+
+func (c *Config) validateLogging(v *validator) {
+	v.required("logging.path", c.Logging.Path)
+	v.required("logging.pattern", c.Logging.Pattern)
+	v.duration("logging.max_age", c.Logging.MaxAge)
+	v.duration("logging.rotation_time", c.Logging.RotationTime)
+	v.size("logging.rotation_size", c.Logging.RotationSize)
+}
+
+func (c *Config) validateGateway(v *validator) {
+	mainOrTest := "test"
+	if !c.Debug {
+		mainOrTest = "main"
+	}
+	if _, ok := c.Gateway.Databases[mainOrTest]; !ok {
+		v.add("gateway.databases."+mainOrTest, "is required when debug is %v", c.Debug)
+	}
+	names := make([]string, 0, len(c.Gateway.Databases))
+	for name := range c.Gateway.Databases {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		database := c.Gateway.Databases[name]
+		path := "gateway.databases." + name
+		if v.required(path+".type", database.Type) && !db.IsSupported(database.Type) {
+			v.add(path+".type", "unknown database type `%s`, supported types: %s", database.Type, strings.Join(db.SupportedTypes, ", "))
+		}
+		v.required(path+".db_name", database.DbName)
+		if strings.ToLower(database.Type) != "sqlite3" {
+			v.required(path+".host", database.Host)
+			v.port(path+".port", database.Port)
+		}
+	}
+
+	usesTLS := false
+	if len(c.Gateway.Listeners) == 0 {
+		v.required("gateway.ip", c.Gateway.IP)
+		v.port("gateway.port", c.Gateway.Port)
+		usesTLS = c.Gateway.TLS.Enabled
+	}
+	listenerNames := map[string]bool{}
+	for i, listener := range c.Gateway.Listeners {
+		path := fmt.Sprintf("gateway.listeners[%d]", i)
+		if v.required(path+".name", listener.Name) {
+			if listenerNames[listener.Name] {
+				v.add(path+".name", "`%s` is used by another listener", listener.Name)
+			}
+			listenerNames[listener.Name] = true
+		}
+		v.choice(path+".network", listener.Network, "tcp", "unix")
+		v.required(path+".address", listener.Address)
+		if listener.Permissions != "" {
+			if _, err := strconv.ParseUint(listener.Permissions, 8, 32); err != nil {
+				v.add(path+".permissions", "`%s` is not a valid file mode, like \"0660\"", listener.Permissions)
+			}
+		}
+		usesTLS = usesTLS || listener.TLS
+	}
+
+	if admin := c.Gateway.Admin; admin != "" {
+		found := false
+		for _, listener := range c.Gateway.GetListeners() {
+			found = found || listener.Name == admin
+		}
+		if !found {
+			v.add("gateway.admin_listener", "`%s` is not a listener", admin)
+		}
+	}
+
+	tls := c.Gateway.TLS
+	if !usesTLS && !tls.Enabled {
+		return
+	}
+	v.required("gateway.tls.cert_file", tls.CertFile)
+	v.required("gateway.tls.key_file", tls.KeyFile)
+	if !secure.IsValidMinVersion(tls.MinVersion) {
+		v.add("gateway.tls.min_version", "`%s` is not one of 1.0, 1.1, 1.2, 1.3", tls.MinVersion)
+	}
+	if !secure.IsValidClientAuth(tls.ClientAuth) {
+		v.add("gateway.tls.client_auth", "`%s` is not one of none, request, require, verify_if_given, require_and_verify", tls.ClientAuth)
+	}
+	if _, err := secure.ParseCipherSuites(tls.CipherSuites); err != nil {
+		v.add("gateway.tls.cipher_suites", "%v", err)
+	}
+	if tls.RedirectPort != "" {
+		v.port("gateway.tls.redirect_port", tls.RedirectPort)
+		if _, ok := c.Gateway.HTTPSListener(); !ok {
+			v.add("gateway.tls.redirect_port", "no tcp listener serves https to redirect to")
+		}
+	}
+	v.min("gateway.tls.reload_interval", tls.ReloadInterval, 0)
+	v.min("gateway.tls.hsts_max_age", tls.HSTSMaxAge, 0)
+}
+
+func (c *Config) validateSupervisor(v *validator) {
+	if c.ClonesCount == 0 {
+		return
+	}
+	v.choice("supervisor.mode", c.Supervisor.Mode, "fail_fast", "restart")
+	v.min("supervisor.max_restarts", int64(c.Supervisor.MaxRestarts), 0)
+	v.min("supervisor.restart_window", c.Supervisor.RestartWindow, 1)
+	v.min("supervisor.min_backoff", c.Supervisor.MinBackoff, 1)
+	v.min("supervisor.max_backoff", c.Supervisor.MaxBackoff, c.Supervisor.MinBackoff)
+	v.min("supervisor.ready_timeout", c.Supervisor.ReadyTimeout, 1)
+	v.min("supervisor.drain_timeout", c.Supervisor.DrainTimeout, 1)
+	if c.Supervisor.StatusAddress != "" {
+		if _, port, err := net.SplitHostPort(c.Supervisor.StatusAddress); err != nil {
+			v.add("supervisor.status_address", "`%s` is not like ip:port", c.Supervisor.StatusAddress)
+		} else {
+			v.port("supervisor.status_address", port)
+		}
+	}
+}
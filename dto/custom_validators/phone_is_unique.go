@@ -17,7 +17,8 @@ func PhoneIsUnique(input interface{}) bool {
 	defer db.Close()
 
 	user := models.NewUser()
-	err = user.Select(map[string]any{
+	// deleted users still hold their unique columns
+	err = user.WithDeleted().Select(map[string]any{
 		"phone_number": input.(string),
 	}).ExecQueryRowErr(context.TODO(), db)
 	if err != nil {
@@ -5,6 +5,7 @@ import (
 
 	"service/config"
 
+	pkgConfig "service/pkg/config"
 	db "service/pkg/database"
 	"service/pkg/logging"
 	media_manager "service/pkg/media"
@@ -39,8 +40,13 @@ var (
 	UuidRegex string = `[0-9a-f]{8}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{4}-[0-9a-f]{12}`
 )
 
-// Config
-var CFG *config.Config = nil
+// Config, swapped atomically on hot reload
+var Configs = pkgConfig.NewStore[config.Config](nil)
+
+// Returns current config, never change the returned value
+func CFG() *config.Config {
+	return Configs.Load()
+}
 
 // SecretKey in bytes
 var SecretKeyBytes []byte
@@ -2,11 +2,14 @@ package auth_handlers
 
 import (
 	"database/sql"
+	rawErrors "errors"
 	"net/http"
 	"service/dto"
 	g "service/global"
 	"service/models"
 	"service/pkg/copier"
+	"service/pkg/errors"
+	"service/pkg/repositories"
 	"service/pkg/translator"
 	"service/utils"
 
@@ -20,15 +23,17 @@ func Register(ctx iris.Context) {
 	user := models.NewUser()
 	copier.Copy(user, req)
 
-	// Activate user & hash the password
-	user.HashMyPassword()
+	// Activate user, the password gets hashed on insert
 	user.IsActive = true
 
-	// Create User
-	user.InsertInto().ExecQuery(ctx, db)
-	user.Select(map[string]any{
-		"phone_number": req.PhoneNumber,
-	}).ExecQueryRow(ctx, db)
+	// Create User, phone number could get taken after it got validated
+	if _, err := user.InsertInto().ExecQueryErr(ctx, db); err != nil {
+		var unique *repositories.ErrUniqueViolation
+		if rawErrors.As(err, &unique) && unique.Column == "phone_number" {
+			panic(errors.New(errors.ConflictStatus, "PhoneIsUnique", err.Error()))
+		}
+		panic(repositories.ServerError(err))
+	}
 	ctx.StatusCode(http.StatusCreated)
 	utils.SendMessage(ctx, translate, "RegisterationFinishedSuccessfully", map[string]any{
 		"user": user,
@@ -2,20 +2,23 @@ package handlers
 
 import (
 	"database/sql"
+	"net/url"
 	"service/dto"
 	g "service/global"
 	"service/models"
+	"service/pkg/errors"
+	"service/pkg/listquery"
+	"service/pkg/repositories"
 	"service/pkg/translator"
 	"service/utils"
+	"strings"
 
 	"github.com/kataras/iris/v12"
 )
 
 var (
 	defaultUsersParams = dto.PaginationUsers{
-		OrderBy: "id",
 		Search:  "",
-		Sort:    "asc",
 		PerPage: 10,
 		Page:    1,
 	}
@@ -23,35 +26,94 @@ var (
 
 func Users(ctx iris.Context) {
 	// Get required data from context
-	user := ctx.Values().Get(g.UserKey).(*models.User)
 	db := ctx.Values().Get(g.DbInstance).(*sql.DB)
 	translate := ctx.Values().Get(g.TranslateKey).(translator.TranslatorFunc)
 
 	// Initialize params and validate them
 	params := &dto.PaginationUsers{
-		OrderBy: ctx.URLParamDefault("order_by", defaultUsersParams.OrderBy),
 		Search:  ctx.URLParamDefault("search", defaultUsersParams.Search),
-		Sort:    ctx.URLParamDefault("sort", defaultUsersParams.Sort),
 		PerPage: ctx.URLParamIntDefault("per_page", defaultUsersParams.PerPage),
 		Page:    ctx.URLParamIntDefault("page", defaultUsersParams.Page),
 	}
 	utils.Validate(params, dto.PaginationUsersValidator, translate)
 
+	// Parse filters, sorts and fields of the list
+	list, err := listquery.Parse(legacySort(ctx.Request().URL.Query()), models.UserListSpec)
+	if err != nil {
+		panic(errors.New(errors.InvalidStatus, "InvalidListQuery", err.Error(), err))
+	}
+
 	// Generate where search text
-	selectParams := map[string]string{
-		"display_name": params.Search,
-		"phone_number": params.Search,
-		"email":        params.Search,
-		"first_name":   params.Search,
-		"last_name":    params.Search,
+	where := repositories.Or(
+		repositories.Like("display_name", "%"+params.Search+"%"),
+		repositories.Like("phone_number", "%"+params.Search+"%"),
+		repositories.Like("email", "%"+params.Search+"%"),
+		repositories.Like("first_name", "%"+params.Search+"%"),
+		repositories.Like("last_name", "%"+params.Search+"%"),
+	)
+	if list.Where != nil {
+		where = repositories.And(where, list.Where)
+	}
+	users := models.Users(db)
+
+	// Cursor pagination doesn't count users and pages don't shift on
+	// inserts, pages get ordered by the first sort column and id
+	if ctx.URLParamExists("cursor") {
+		page, err := users.FindPage(ctx, where, repositories.CursorOptions{
+			OrderBy: list.Sort[0].Column,
+			Desc:    list.Sort[0].Desc,
+			Limit:   params.PerPage,
+			Cursor:  ctx.URLParam("cursor"),
+			Key:     g.SecretKeyBytes,
+		})
+		if err != nil {
+			panic(repositories.ServerError(err))
+		}
+		data, err := list.Project(page.Rows)
+		if err != nil {
+			utils.Panic500(err)
+		}
+		utils.SendCursorPage(ctx, params.PerPage, page.Next, page.Prev, data)
+		return
 	}
-	wheres := user.GetLikeWheres(selectParams)
 
-	// Get count of all users and all users in that spacific page
-	users := &[]*models.User{}
-	usersCount := user.SelectCount().ExecQueryCount(ctx, db)
-	user.SelectWhere(wheres).OrderBy(params.OrderBy, params.Sort).Paginate(params.PerPage, params.Page).ExecQueryMulti(ctx, db, users)
+	// Get count of all matching users and all users in that spacific page
+	usersCount, err := users.Count(ctx, where)
+	if err != nil {
+		panic(repositories.ServerError(err))
+	}
+	rows, err := users.FindMany(ctx, where, repositories.FindOptions{
+		Sort:   list.Sort,
+		Limit:  params.PerPage,
+		Offset: (params.Page - 1) * params.PerPage,
+	})
+	if err != nil {
+		panic(repositories.ServerError(err))
+	}
+	data, err := list.Project(rows)
+	if err != nil {
+		utils.Panic500(err)
+	}
 
 	// Create and send the page
-	utils.SendPage(ctx, usersCount, params.PerPage, params.Page, users)
+	utils.SendPage(ctx, usersCount, params.PerPage, params.Page, data)
+}
+
+// Maps old `order_by=column&sort=asc|desc` parameters onto sort parameter
+// of listquery, so old clients keep working
+func legacySort(values url.Values) url.Values {
+	sort := strings.ToLower(values.Get("sort"))
+	if !values.Has("order_by") && sort != "asc" && sort != "desc" {
+		return values
+	}
+	column := values.Get("order_by")
+	if column == "" {
+		column = "id"
+	}
+	if sort == "desc" {
+		column = "-" + column
+	}
+	values.Del("order_by")
+	values.Set("sort", column)
+	return values
 }
@@ -1,9 +1,11 @@
 package main
 
 import (
+	"os"
+
 	"service/app"
 )
 
 func main() {
-	app.API()
+	app.Execute(os.Args[1:])
 }
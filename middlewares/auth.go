@@ -6,6 +6,7 @@ import (
 	g "service/global"
 	"service/models"
 	"service/pkg/errors"
+	"service/pkg/repositories"
 	"service/utils"
 	"strconv"
 	"strings"
@@ -54,12 +55,7 @@ func Auth(ctx iris.Context) {
 	}
 
 	// Check that token inside database too
-	token := &models.Token{
-		Id:    tokenId,
-		Token: tokenString,
-	}
-	token.InformMeToQueryProvider()
-	err = token.GetMe().ExecQueryRowErr(ctx, db)
+	token, err := models.Tokens(db).FindByID(ctx, tokenId, repositories.FindOptions{Preload: []string{"User"}})
 	if err != nil {
 		if sqlscan.NotFound(err) {
 			panic(errors.New(errors.UnauthorizedStatus, "LoginPlease", err.Error()))
@@ -68,13 +64,14 @@ func Auth(ctx iris.Context) {
 		}
 	}
 	if token.Token != tokenString {
-		panic(errors.New(errors.UnauthorizedStatus, "LoginPlease", err.Error()))
+		panic(errors.New(errors.UnauthorizedStatus, "LoginPlease", "token is not valid"))
 	}
 
 	// Now that everything is fine, get user instance
-	user := models.NewUser()
-	user.Id = claims.UserId
-	user.GetMe().ExecQueryRow(ctx, db)
+	user := token.User
+	if user == nil || user.Id != claims.UserId {
+		panic(errors.New(errors.UnauthorizedStatus, "LoginPlease", "user of token doesn't exist"))
+	}
 
 	// Set user instance and token into context
 	ctx.Values().Set(g.UserKey, user)
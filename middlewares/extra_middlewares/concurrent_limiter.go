@@ -1,20 +1,30 @@
 package extra_middlewares
 
 import (
+	"sync/atomic"
+
 	"github.com/kataras/iris/v12"
 )
 
-var sem chan struct{} = nil
+var sem atomic.Pointer[chan struct{}]
+
+// Changes max concurrent requests, requests which are already
+// running keep their slots in the old semaphore
+func SetMaxConcurrentRequests(maxConcurrentRequests int) {
+	s := make(chan struct{}, maxConcurrentRequests)
+	sem.Store(&s)
+}
 
 func ConcurrentLimiter(maxConcurrentRequests int) iris.Handler {
-	if sem == nil {
-		sem = make(chan struct{}, maxConcurrentRequests)
+	if sem.Load() == nil {
+		SetMaxConcurrentRequests(maxConcurrentRequests)
 	}
 
 	return func(ctx iris.Context) {
-		sem <- struct{}{} // Acquire a semaphore slot
+		s := *sem.Load()
+		s <- struct{}{} // Acquire a semaphore slot
 		defer func() {
-			<-sem // Release the semaphore slot
+			<-s // Release the semaphore slot
 		}()
 
 		ctx.Next()
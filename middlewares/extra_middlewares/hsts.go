@@ -0,0 +1,22 @@
+package extra_middlewares
+
+import (
+	"fmt"
+
+	"github.com/kataras/iris/v12"
+)
+
+func HSTS(maxAge int64, includeSubdomains, preload bool) iris.Handler {
+	value := fmt.Sprintf("max-age=%d", maxAge)
+	if includeSubdomains {
+		value += "; includeSubDomains"
+	}
+	if preload {
+		value += "; preload"
+	}
+
+	return func(ctx iris.Context) {
+		ctx.Header("Strict-Transport-Security", value)
+		ctx.Next()
+	}
+}
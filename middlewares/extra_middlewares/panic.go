@@ -10,6 +10,7 @@ import (
 	g "service/global"
 
 	"service/pkg/errors"
+	"service/pkg/repositories"
 	"service/pkg/translator"
 
 	"github.com/kataras/iris/v12"
@@ -23,6 +24,10 @@ func Panic(ctx iris.Context) {
 		if errInterface == nil {
 			return
 		}
+		// errors of the database get statuses by their class, like 404 or 409
+		if err, ok := errInterface.(error); ok && !errors.IsServerError(err) && repositories.IsClassified(err) {
+			errInterface = repositories.ServerError(err)
+		}
 
 		writerLock := ctx.Values().Get(g.WriterLock).(*sync.Mutex)
 		writerLock.Lock()
@@ -44,7 +49,7 @@ func Panic(ctx iris.Context) {
 						Action:  action,
 						Errors:  errors,
 					}
-					if g.CFG.Debug {
+					if g.CFG().Debug {
 						log.Println(err)
 					}
 					ctx.StopWithJSON(res.Code, res)
@@ -55,7 +60,7 @@ func Panic(ctx iris.Context) {
 						Action:  action,
 						Errors:  errors,
 					}
-					if g.CFG.Debug {
+					if g.CFG().Debug {
 						log.Println(err)
 					}
 					ctx.StopWithJSON(code, res)
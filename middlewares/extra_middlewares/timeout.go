@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	g "service/global"
@@ -12,7 +13,16 @@ import (
 	"github.com/kataras/iris/v12"
 )
 
-func Timeout(timeout time.Duration) iris.Handler {
+var timeout atomic.Int64
+
+// Changes timeout of the requests which start after calling it
+func SetTimeout(d time.Duration) {
+	timeout.Store(int64(d))
+}
+
+func Timeout(d time.Duration) iris.Handler {
+	SetTimeout(d)
+
 	return func(ctx iris.Context) {
 		// Create a channel to wait for the handler to complete.
 		ch := make(chan any, 1)
@@ -41,7 +51,7 @@ func Timeout(timeout time.Duration) iris.Handler {
 			close(ch)
 		}()
 
-		newCtx, cancel := context.WithTimeout(ctx.Request().Context(), timeout)
+		newCtx, cancel := context.WithTimeout(ctx.Request().Context(), time.Duration(timeout.Load()))
 		ctx.ResetRequest(ctx.Request().WithContext(newCtx))
 		defer cancel()
 
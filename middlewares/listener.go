@@ -0,0 +1,39 @@
+package middlewares
+
+import (
+	"context"
+	"fmt"
+	"service/pkg/errors"
+
+	"github.com/kataras/iris/v12"
+)
+
+type listenerKey struct{}
+
+// Records name of the listener which accepted the connection into its context
+func WithListener(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, listenerKey{}, name)
+}
+
+// Returns name of the listener which accepted the request
+func GetListener(ctx iris.Context) string {
+	name, _ := ctx.Request().Context().Value(listenerKey{}).(string)
+	return name
+}
+
+// Binds routes to listeners with passed names, requests
+// coming from any other listener get 404
+func OnlyListeners(names ...string) iris.Handler {
+	allowed := map[string]bool{}
+	for _, name := range names {
+		allowed[name] = true
+	}
+
+	return func(ctx iris.Context) {
+		if name := GetListener(ctx); !allowed[name] {
+			panic(errors.New(errors.NotFoundStatus, "RouteNotFound", fmt.Sprintf("route is not served on `%s` listener", name)))
+		}
+
+		ctx.Next()
+	}
+}
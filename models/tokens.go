@@ -18,19 +18,19 @@ type Token struct {
 	Token          string    `json:"token" db:"token" skipUpdate:"+"`
 	IsRefreshToken bool      `json:"is_refresh_token" db:"is_refresh_token" skipUpdate:"+"`
 	UserId         *int64    `json:"-" db:"user_id" skipUpdate:"+" nilOnEmpty:"+"`
-	User           *User     `json:"-"`
+	User           *User     `json:"-" belongsTo:"user_id"`
 	ExpiresAt      time.Time `json:"expires_at" db:"expires_at" skipUpdate:"+"`
-	CreatedAt      time.Time `json:"created_at" db:"created_at" skipUpdate:"+"`
+	CreatedAt      time.Time `json:"created_at" db:"created_at" autoCreateTime:"+"`
+}
+
+func (*Token) TableName() string {
+	return TokenName
 }
 
 func (t *Token) GetUser(ctx iris.Context, db *sql.DB) *User {
-	if t.User == nil {
-		user := NewUser()
-		user.Id = *t.UserId
-		t.User = user
+	if err := Tokens(db).Preload(ctx, []*Token{t}, "User"); err != nil {
+		panic(repositories.ServerError(err))
 	}
-
-	t.User.GetMe().ExecQueryRow(ctx, db)
 	return t.User
 }
 
@@ -41,6 +41,11 @@ func (t *Token) InformMeToQueryProvider() *Token {
 	return t
 }
 
+// Returns the typed repository of tokens
+func Tokens(db *sql.DB) *repositories.Repository[*Token] {
+	return repositories.NewRepository[*Token](db, g.MainDatabaseType)
+}
+
 func NewToken(accessRefreshToken string, isRefreshToken bool, expiresAt time.Time, userId int64) *Token {
 	user := NewUser()
 	user.Id = userId
@@ -52,8 +57,8 @@ func NewToken(accessRefreshToken string, isRefreshToken bool, expiresAt time.Tim
 		UserId:         &userId,
 		User:           user,
 		ExpiresAt:      expiresAt,
-		CreatedAt:      time.Now(),
 	}
 	token.SetRowData(token)
+	token.SetDbType(g.MainDatabaseType)
 	return token
 }
@@ -1,9 +1,11 @@
 package models
 
 import (
+	"context"
 	"database/sql"
 	"fmt"
 	g "service/global"
+	"service/pkg/listquery"
 	"service/pkg/repositories"
 	"time"
 
@@ -19,13 +21,17 @@ type UserInternal struct {
 
 	Id          int64     `json:"id" db:"id" skipInsert:"+"`
 	DisplayName string    `json:"display_name" db:"display_name"`
-	CreatedAt   time.Time `json:"created_at" db:"created_at" skipUpdate:"+"`
+	CreatedAt   time.Time `json:"created_at" db:"created_at" autoCreateTime:"+"`
+	UpdatedAt   time.Time `json:"updated_at" db:"updated_at" autoUpdateTime:"+"`
+}
+
+func (*UserInternal) TableName() string {
+	return UserName
 }
 
 func NewUserInternal() *UserInternal {
 	user := &UserInternal{
 		QueryGenerator: repositories.NewQueryGenerator(UserName),
-		CreatedAt:      time.Now(),
 	}
 	user.SetRowData(user)
 	user.SetDbType(g.MainDatabaseType)
@@ -43,10 +49,15 @@ type User struct {
 	IsActive    bool   `json:"-" db:"is_active"`
 	IsAdmin     bool   `json:"-" db:"is_admin"`
 	IsSuperuser bool   `json:"-" db:"is_superuser"`
+
+	DeletedAt *time.Time `json:"-" db:"deleted_at" softDelete:"+"`
+	Version   int64      `json:"-" db:"version" version:"+"`
+
+	Tokens []*Token `json:"-" hasMany:"user_id"`
 }
 
 func (u *User) CreateAccessToken(ctx iris.Context, db *sql.DB) *Token {
-	expirationTime := time.Now().Add(time.Duration(g.CFG.AccessTokenLifePeriod) * (time.Hour * 24))
+	expirationTime := time.Now().Add(time.Duration(g.CFG().AccessTokenLifePeriod) * (time.Hour * 24))
 
 	claims := &Claims{
 		UserId: u.Id,
@@ -59,16 +70,14 @@ func (u *User) CreateAccessToken(ctx iris.Context, db *sql.DB) *Token {
 	tkn := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, _ := tkn.SignedString(g.SecretKeyBytes)
 	token := NewToken(tokenString, false, expirationTime, u.Id)
-	if token.InsertInto().ExecQuery(ctx, db) == 0 {
-		token.Select(map[string]any{"token": token.Token, "user_id": u.Id}).ExecQueryRow(ctx, db)
-	}
+	token.InsertInto().ExecQuery(ctx, db)
 	token.Token = fmt.Sprintf("%d|%s", token.Id, token.Token)
 	token.User = u
 	return token
 }
 
 func (u *User) CreateRefreshToken(ctx iris.Context, db *sql.DB) *Token {
-	expirationTime := time.Now().Add(time.Duration(g.CFG.RefreshTokenLifePeriod) * (time.Hour * 24 * 30))
+	expirationTime := time.Now().Add(time.Duration(g.CFG().RefreshTokenLifePeriod) * (time.Hour * 24 * 30))
 
 	claims := &Claims{
 		UserId: u.Id,
@@ -81,9 +90,7 @@ func (u *User) CreateRefreshToken(ctx iris.Context, db *sql.DB) *Token {
 	tkn := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	tokenString, _ := tkn.SignedString(g.SecretKeyBytes)
 	token := NewToken(tokenString, true, expirationTime, u.Id)
-	if token.InsertInto().ExecQuery(ctx, db) == 0 {
-		token.Select(map[string]any{"token": token.Token, "user_id": u.Id}).ExecQueryRow(ctx, db)
-	}
+	token.InsertInto().ExecQuery(ctx, db)
 	token.Token = fmt.Sprintf("%d|%s", token.Id, token.Token)
 	token.User = u
 	return token
@@ -115,6 +122,25 @@ func (u *User) IsPasswordEqualToMyHash(password string) bool {
 	return true
 }
 
+// Hashes the password of new users unless it is hashed, see repositories.BeforeInserter
+func (u *User) BeforeInsert(ctx context.Context, db repositories.DB) error {
+	u.hashPlainPassword()
+	return nil
+}
+
+// Hashes the password if it got set to a plain one, see repositories.BeforeUpdater
+func (u *User) BeforeUpdate(ctx context.Context, db repositories.DB) error {
+	u.hashPlainPassword()
+	return nil
+}
+
+// Rows can be saved with their loaded password, which is hashed already
+func (u *User) hashPlainPassword() {
+	if _, err := bcrypt.Cost([]byte(u.Password)); err != nil {
+		u.HashMyPassword()
+	}
+}
+
 func (u *User) InformMeToQueryProvider() *User {
 	u.QueryGenerator = repositories.NewQueryGenerator(UserName)
 	u.SetRowData(u)
@@ -122,11 +148,31 @@ func (u *User) InformMeToQueryProvider() *User {
 	return u
 }
 
+// What lists of users can get filtered, sorted and selected by
+var UserListSpec = listquery.Spec{
+	Model: User{},
+	Filters: map[string][]string{
+		"id":           {listquery.Eq, listquery.In, listquery.Gt, listquery.Lt},
+		"display_name": {listquery.Eq, listquery.Like},
+		"phone_number": {listquery.Eq, listquery.Like},
+		"email":        {listquery.Eq, listquery.Like, listquery.Null},
+		"created_at":   {listquery.Gt, listquery.Gte, listquery.Lt, listquery.Lte, listquery.Between},
+		"is_active":    {listquery.Eq},
+	},
+	Sorts:       []string{"id", "display_name", "created_at"},
+	Fields:      []string{"id", "display_name", "created_at", "phone_number", "email", "first_name", "last_name"},
+	DefaultSort: []repositories.Sort{{Column: "id"}},
+}
+
+// Returns the typed repository of users
+func Users(db *sql.DB) *repositories.Repository[*User] {
+	return repositories.NewRepository[*User](db, g.MainDatabaseType)
+}
+
 func NewUser() *User {
 	user := &User{
 		UserInternal: UserInternal{
 			QueryGenerator: repositories.NewQueryGenerator(UserName),
-			CreatedAt:      time.Now(),
 		},
 	}
 	user.SetRowData(user)
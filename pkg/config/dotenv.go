@@ -0,0 +1,82 @@
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Overrides fields of `cfg` with variables of a dotenv file, names are
+// the same as environment variables (see ParseEnv), like:
+//
+//	# comment
+//	export APP_TIMEOUT=20
+//	APP_GATEWAY_DATABASES_MAIN_PASSWORD="p@ss # not a comment"
+func ParseDotenv(path string, prefix string, cfg interface{}, errorOnFileNotFound bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errorOnFileNotFound {
+			return err
+		}
+		return nil
+	}
+	return ParseDotenvBytes(data, prefix, cfg)
+}
+
+// Same as ParseDotenv but reads variables from `data`
+func ParseDotenvBytes(data []byte, prefix string, cfg interface{}) error {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: cfg has to be a pointer to struct")
+	}
+
+	envs, err := parseDotenv(data)
+	if err != nil {
+		return err
+	}
+	return parseEnvValue(value.Elem(), strings.ToUpper(prefix), envs)
+}
+
+// Returns variables of a dotenv file as a map
+func parseDotenv(data []byte) (map[string]string, error) {
+	envs := map[string]string{}
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	for number := 1; scanner.Scan(); number++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		key, value, ok := strings.Cut(line, "=")
+		key = strings.TrimSpace(key)
+		if !ok || key == "" {
+			return nil, fmt.Errorf("config: line %d of dotenv is not like KEY=VALUE", number)
+		}
+
+		value = strings.TrimSpace(value)
+		switch {
+		case strings.HasPrefix(value, `"`):
+			unquoted, err := strconv.Unquote(value)
+			if err != nil {
+				return nil, fmt.Errorf("config: line %d of dotenv: invalid quoted value", number)
+			}
+			value = unquoted
+		case strings.HasPrefix(value, "'"):
+			if len(value) < 2 || !strings.HasSuffix(value, "'") {
+				return nil, fmt.Errorf("config: line %d of dotenv: invalid quoted value", number)
+			}
+			value = value[1 : len(value)-1]
+		default:
+			if i := strings.Index(value, " #"); i != -1 {
+				value = strings.TrimSpace(value[:i])
+			}
+		}
+		envs[key] = value
+	}
+	return envs, scanner.Err()
+}
@@ -0,0 +1,271 @@
+package config
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// Suffix of environment variables which point to a file containing the value
+const fileSuffix = "_FILE"
+
+// Overrides fields of `cfg` with environment variables
+//
+// Name of every variable is `prefix` and yaml tags of the path to the
+// field joined with `_` in upper case, like: APP_GATEWAY_DATABASES_MAIN_PASSWORD
+// for `gateway.databases.main.password`
+//
+// Every variable also has a `_FILE` variant which its value is path of a
+// file containing the value, useful for secrets like APP_SECRET_KEY_FILE
+//
+// Slices of simple types get separated by `,` and slices of structs
+// get indexed like: APP_GATEWAY_LISTENERS_0_ADDRESS
+func ParseEnv(prefix string, cfg interface{}) error {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: cfg has to be a pointer to struct")
+	}
+
+	return parseEnvValue(value.Elem(), strings.ToUpper(prefix), environ())
+}
+
+// Joins name of the parent and the field, root fields have no parent when prefix is empty
+func joinEnvName(name string, field string) string {
+	if name == "" {
+		return strings.ToUpper(field)
+	}
+	return name + "_" + strings.ToUpper(field)
+}
+
+// Returns environment variables as a map
+func environ() map[string]string {
+	envs := map[string]string{}
+	for _, env := range os.Environ() {
+		if key, value, ok := strings.Cut(env, "="); ok {
+			envs[key] = value
+		}
+	}
+	return envs
+}
+
+// Returns value of `name` variable or content of file which
+// `name_FILE` variable points to
+func lookupEnv(envs map[string]string, name string) (string, bool, error) {
+	if value, ok := envs[name]; ok {
+		return value, true, nil
+	}
+	if path, ok := envs[name+fileSuffix]; ok {
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return "", false, fmt.Errorf("config: %s: %w", name+fileSuffix, err)
+		}
+		return strings.TrimRight(string(content), "\r\n"), true, nil
+	}
+	return "", false, nil
+}
+
+// Returns name of the field in yaml, empty if field is ignored
+func fieldName(field reflect.StructField) string {
+	if !field.IsExported() {
+		return ""
+	}
+	name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+	if name == "-" {
+		return ""
+	}
+	if name == "" {
+		return strings.ToLower(field.Name)
+	}
+	return name
+}
+
+func parseEnvValue(value reflect.Value, name string, envs map[string]string) error {
+	switch value.Kind() {
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := fieldName(value.Type().Field(i))
+			if field == "" {
+				continue
+			}
+			if err := parseEnvValue(value.Field(i), joinEnvName(name, field), envs); err != nil {
+				return err
+			}
+		}
+		return nil
+	case reflect.Map:
+		return parseEnvMap(value, name, envs)
+	case reflect.Slice:
+		if isSimple(value.Type().Elem().Kind()) {
+			break
+		}
+		return parseEnvSlice(value, name, envs)
+	case reflect.Ptr:
+		if value.IsNil() {
+			elem := reflect.New(value.Type().Elem())
+			if err := parseEnvValue(elem.Elem(), name, envs); err != nil {
+				return err
+			}
+			if !elem.Elem().IsZero() {
+				value.Set(elem)
+			}
+			return nil
+		}
+		return parseEnvValue(value.Elem(), name, envs)
+	}
+
+	raw, ok, err := lookupEnv(envs, name)
+	if err != nil || !ok {
+		return err
+	}
+	if err := setValue(value, raw); err != nil {
+		return fmt.Errorf("config: %s: %w", name, err)
+	}
+	return nil
+}
+
+// Map keys are not known beforehand, so keys get extracted from
+// variables which start with `name_` and end with one of the fields
+func parseEnvMap(value reflect.Value, name string, envs map[string]string) error {
+	elemType := value.Type().Elem()
+	fields := envNames(elemType, "")
+
+	keys := map[string]bool{}
+	for env := range envs {
+		env = strings.TrimSuffix(env, fileSuffix)
+		rest, ok := strings.CutPrefix(env, name+"_")
+		if !ok || rest == "" {
+			continue
+		}
+		if len(fields) == 0 {
+			keys[rest] = true
+			continue
+		}
+		for _, field := range fields {
+			if key, ok := strings.CutSuffix(rest, field); ok && key != "" {
+				keys[key] = true
+			}
+		}
+	}
+
+	if len(keys) != 0 && value.IsNil() {
+		value.Set(reflect.MakeMap(value.Type()))
+	}
+	for key := range keys {
+		mapKey := reflect.ValueOf(strings.ToLower(key)).Convert(value.Type().Key())
+		elem := reflect.New(elemType).Elem()
+		if existing := value.MapIndex(mapKey); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := parseEnvValue(elem, name+"_"+key, envs); err != nil {
+			return err
+		}
+		value.SetMapIndex(mapKey, elem)
+	}
+	return nil
+}
+
+// Slices of structs get indexed like `name_0_field`
+func parseEnvSlice(value reflect.Value, name string, envs map[string]string) error {
+	fields := envNames(value.Type().Elem(), "")
+	for i := 0; ; i++ {
+		prefix := fmt.Sprintf("%s_%d", name, i)
+		found := false
+		for _, field := range fields {
+			if _, ok := envs[prefix+field]; ok {
+				found = true
+			} else if _, ok := envs[prefix+field+fileSuffix]; ok {
+				found = true
+			}
+		}
+		if !found {
+			return nil
+		}
+
+		for value.Len() <= i {
+			value.Set(reflect.Append(value, reflect.New(value.Type().Elem()).Elem()))
+		}
+		if err := parseEnvValue(value.Index(i), prefix, envs); err != nil {
+			return err
+		}
+	}
+}
+
+// Returns all variable name suffixes which a type can have, like `_PASSWORD`
+func envNames(t reflect.Type, name string) []string {
+	for t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	if t.Kind() != reflect.Struct {
+		if name == "" {
+			return nil
+		}
+		return []string{name}
+	}
+
+	names := []string{}
+	for i := 0; i < t.NumField(); i++ {
+		field := fieldName(t.Field(i))
+		if field == "" {
+			continue
+		}
+		names = append(names, envNames(t.Field(i).Type, name+"_"+strings.ToUpper(field))...)
+	}
+	return names
+}
+
+func isSimple(kind reflect.Kind) bool {
+	switch kind {
+	case reflect.Struct, reflect.Map, reflect.Slice, reflect.Ptr, reflect.Interface:
+		return false
+	}
+	return true
+}
+
+// Converts raw string into the value type and sets it
+func setValue(value reflect.Value, raw string) error {
+	switch value.Kind() {
+	case reflect.String:
+		value.SetString(raw)
+	case reflect.Bool:
+		parsed, err := strconv.ParseBool(raw)
+		if err != nil {
+			return err
+		}
+		value.SetBool(parsed)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		parsed, err := strconv.ParseInt(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetInt(parsed)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		parsed, err := strconv.ParseUint(raw, 10, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetUint(parsed)
+	case reflect.Float32, reflect.Float64:
+		parsed, err := strconv.ParseFloat(raw, value.Type().Bits())
+		if err != nil {
+			return err
+		}
+		value.SetFloat(parsed)
+	case reflect.Slice:
+		items := []string{}
+		if strings.TrimSpace(raw) != "" {
+			items = strings.Split(raw, ",")
+		}
+		slice := reflect.MakeSlice(value.Type(), len(items), len(items))
+		for i, item := range items {
+			if err := setValue(slice.Index(i), strings.TrimSpace(item)); err != nil {
+				return err
+			}
+		}
+		value.Set(slice)
+	default:
+		return fmt.Errorf("unsupported type %s", value.Type())
+	}
+	return nil
+}
@@ -5,6 +5,7 @@ import (
 	"os"
 	"path/filepath"
 
+	"github.com/BurntSushi/toml"
 	"gopkg.in/yaml.v3"
 )
 
@@ -15,10 +16,18 @@ var (
 
 // Checks if extention of the file is supported or not and if supported
 // its configurations will be read and will be saved in cfg variable
+//
+// Supported extentions are .yaml, .yml, .json, .toml and .env, keys of
+// all of them are yaml tags of the fields, except .env files which use
+// names of environment variables without prefix, like GATEWAY_PORT=6969
 func Parse(path string, cfg interface{}, errorOnFileNotFound bool) error {
 	switch filepath.Ext(path) {
-	case ".yaml", ".yml":
+	case ".yaml", ".yml", ".json":
 		return parseYaml(path, cfg, errorOnFileNotFound)
+	case ".toml":
+		return parseToml(path, cfg, errorOnFileNotFound)
+	case ".env":
+		return ParseDotenv(path, "", cfg, errorOnFileNotFound)
 	default:
 		return ErrUnknownFileExtention
 	}
@@ -38,6 +47,37 @@ func ParseYamlBytes(data []byte, cfg interface{}) error {
 	return yaml.Unmarshal(data, cfg)
 }
 
+// Json is a subset of yaml, so same decoder is used for it
+func ParseJsonBytes(data []byte, cfg interface{}) error {
+	return yaml.Unmarshal(data, cfg)
+}
+
+// Toml gets decoded into a map and then into `cfg` by yaml decoder, so
+// that yaml tags are used and fields which are not in data stay untouched
+func ParseTomlBytes(data []byte, cfg interface{}) error {
+	values := map[string]interface{}{}
+	if err := toml.Unmarshal(data, &values); err != nil {
+		return err
+	}
+	converted, err := yaml.Marshal(values)
+	if err != nil {
+		return err
+	}
+	return yaml.Unmarshal(converted, cfg)
+}
+
+// Parses configuration data of a toml file
+func parseToml(path string, cfg interface{}, errorOnFileNotFound bool) error {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		if errorOnFileNotFound {
+			return err
+		}
+		return nil
+	}
+	return ParseTomlBytes(data, cfg)
+}
+
 // Parses configuration data of a yaml file
 func parseYaml(path string, cfg interface{}, errorOnFileNotFound bool) error {
 	file, err := os.Open(path)
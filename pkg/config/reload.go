@@ -0,0 +1,103 @@
+package config
+
+import (
+	"os"
+	"os/signal"
+	"reflect"
+	"time"
+)
+
+// Compares two configs of the same type and returns yaml paths of changed
+// fields, `reloadable` ones have `reload:"+"` tag and others need a restart
+// to take effect
+func Changes(old, new interface{}) (reloadable []string, restart []string) {
+	reloadable, restart = []string{}, []string{}
+	changes(reflect.Indirect(reflect.ValueOf(old)), reflect.Indirect(reflect.ValueOf(new)), "", false, &reloadable, &restart)
+	return
+}
+
+func changes(old, new reflect.Value, path string, canReload bool, reloadable, restart *[]string) {
+	if old.Kind() != reflect.Struct {
+		if reflect.DeepEqual(old.Interface(), new.Interface()) {
+			return
+		}
+		if canReload {
+			*reloadable = append(*reloadable, path)
+		} else {
+			*restart = append(*restart, path)
+		}
+		return
+	}
+
+	for i := 0; i < old.NumField(); i++ {
+		field := old.Type().Field(i)
+		name := fieldName(field)
+		if name == "" {
+			continue
+		}
+		if path != "" {
+			name = path + "." + name
+		}
+		changes(old.Field(i), new.Field(i), name, canReload || field.Tag.Get("reload") == "+", reloadable, restart)
+	}
+}
+
+// Copies fields with `reload:"+"` tag from `src` into `dst`
+//
+// Both have to be pointers to the same struct type
+func CopyReloadable(dst, src interface{}) {
+	copyReloadable(reflect.ValueOf(dst).Elem(), reflect.ValueOf(src).Elem())
+}
+
+func copyReloadable(dst, src reflect.Value) {
+	for i := 0; i < dst.NumField(); i++ {
+		field := dst.Type().Field(i)
+		if !field.IsExported() {
+			continue
+		}
+		if field.Tag.Get("reload") == "+" {
+			dst.Field(i).Set(src.Field(i))
+		} else if field.Type.Kind() == reflect.Struct {
+			copyReloadable(dst.Field(i), src.Field(i))
+		}
+	}
+}
+
+// Calls `onChange` whenever one of `paths` gets created, modified or
+// removed (checked every `interval`) or one of `signals` is received
+//
+// Blocks forever, so run it in a goroutine
+func Watch(paths []string, interval time.Duration, signals []os.Signal, onChange func()) {
+	modified := func() []time.Time {
+		times := make([]time.Time, len(paths))
+		for i, path := range paths {
+			if info, err := os.Stat(path); err == nil {
+				times[i] = info.ModTime()
+			}
+		}
+		return times
+	}
+
+	received := make(chan os.Signal, 1)
+	if len(signals) > 0 {
+		signal.Notify(received, signals...)
+	}
+
+	var tick <-chan time.Time = nil
+	if interval > 0 {
+		tick = time.Tick(interval)
+	}
+
+	last := modified()
+	for {
+		select {
+		case <-received:
+		case <-tick:
+			if reflect.DeepEqual(modified(), last) {
+				continue
+			}
+		}
+		last = modified()
+		onChange()
+	}
+}
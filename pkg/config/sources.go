@@ -0,0 +1,230 @@
+package config
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+type (
+	// A place which configs get loaded from, like a file or environment variables
+	Source struct {
+		Name string
+		Load func(cfg interface{}) error
+	}
+
+	// An effective config, `Path` is the yaml path of it and `Source`
+	// is name of the last source which changed it, `Secret` is true for
+	// fields with `secret:"+"` tag which shouldn't get printed
+	Field struct {
+		Path   string
+		Value  string
+		Source string
+		Secret bool
+	}
+)
+
+// Name of the source of configs which no source has changed
+const ZeroSource = "zero value"
+
+// Source of embedded configs, `ext` is extention of the data like ".yaml"
+func BytesSource(name string, data []byte, ext string) Source {
+	return Source{Name: name, Load: func(cfg interface{}) error {
+		switch ext {
+		case ".yaml", ".yml":
+			return ParseYamlBytes(data, cfg)
+		case ".json":
+			return ParseJsonBytes(data, cfg)
+		case ".toml":
+			return ParseTomlBytes(data, cfg)
+		case ".env":
+			return ParseDotenvBytes(data, "", cfg)
+		default:
+			return ErrUnknownFileExtention
+		}
+	}}
+}
+
+// Source of a config file, see Parse for supported extentions
+func FileSource(path string, errorOnFileNotFound bool) Source {
+	return Source{Name: path, Load: func(cfg interface{}) error {
+		return Parse(path, cfg, errorOnFileNotFound)
+	}}
+}
+
+// Source of a dotenv file containing prefixed variables, see ParseDotenv
+func DotenvSource(path string, prefix string, errorOnFileNotFound bool) Source {
+	return Source{Name: path, Load: func(cfg interface{}) error {
+		return ParseDotenv(path, prefix, cfg, errorOnFileNotFound)
+	}}
+}
+
+// Source of environment variables, see ParseEnv
+func EnvSource(prefix string) Source {
+	return Source{Name: "environment", Load: func(cfg interface{}) error {
+		return ParseEnv(prefix, cfg)
+	}}
+}
+
+// Source of overrides like "gateway.port=6969", usually passed as CLI flags
+func OverridesSource(name string, overrides []string) Source {
+	return Source{Name: name, Load: func(cfg interface{}) error {
+		for _, override := range overrides {
+			path, value, ok := strings.Cut(override, "=")
+			if !ok {
+				return fmt.Errorf("config: `%s` is not like path=value", override)
+			}
+			if err := Set(cfg, strings.TrimSpace(path), value); err != nil {
+				return err
+			}
+		}
+		return nil
+	}}
+}
+
+// Loads `sources` into `cfg` in order, so latter ones override former
+// ones, and returns all configs with the source each one came from
+func Load(cfg interface{}, sources ...Source) ([]Field, error) {
+	provenance := map[string]string{}
+	last := flatten(cfg)
+	for _, source := range sources {
+		if err := source.Load(cfg); err != nil {
+			return nil, fmt.Errorf("config: %s: %w", source.Name, err)
+		}
+		current := flatten(cfg)
+		for path, value := range current {
+			if previous, ok := last[path]; !ok || previous != value {
+				provenance[path] = source.Name
+			}
+		}
+		last = current
+	}
+
+	fields := Flatten(cfg)
+	for i := range fields {
+		if source, ok := provenance[fields[i].Path]; ok {
+			fields[i].Source = source
+		} else {
+			fields[i].Source = ZeroSource
+		}
+	}
+	return fields, nil
+}
+
+// Returns all configs of `cfg` with their yaml path in order of fields,
+// map entries are sorted by their key
+func Flatten(cfg interface{}) []Field {
+	fields := []Field{}
+	flattenValue(reflect.ValueOf(cfg), "", false, &fields)
+	return fields
+}
+
+func flatten(cfg interface{}) map[string]string {
+	values := map[string]string{}
+	for _, field := range Flatten(cfg) {
+		values[field.Path] = field.Value
+	}
+	return values
+}
+
+func joinPath(path string, name string) string {
+	if path == "" {
+		return name
+	}
+	return path + "." + name
+}
+
+func flattenValue(value reflect.Value, path string, secret bool, fields *[]Field) {
+	switch value.Kind() {
+	case reflect.Ptr, reflect.Interface:
+		if !value.IsNil() {
+			flattenValue(value.Elem(), path, secret, fields)
+		}
+		return
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			field := value.Type().Field(i)
+			if name := fieldName(field); name != "" {
+				flattenValue(value.Field(i), joinPath(path, name), secret || field.Tag.Get("secret") == "+", fields)
+			}
+		}
+		return
+	case reflect.Map:
+		keys := value.MapKeys()
+		sort.Slice(keys, func(i, j int) bool {
+			return fmt.Sprint(keys[i].Interface()) < fmt.Sprint(keys[j].Interface())
+		})
+		for _, key := range keys {
+			flattenValue(value.MapIndex(key), joinPath(path, fmt.Sprint(key.Interface())), secret, fields)
+		}
+		return
+	case reflect.Slice:
+		if !isSimple(value.Type().Elem().Kind()) {
+			for i := 0; i < value.Len(); i++ {
+				flattenValue(value.Index(i), joinPath(path, strconv.Itoa(i)), secret, fields)
+			}
+			return
+		}
+	}
+	*fields = append(*fields, Field{Path: path, Value: fmt.Sprint(value.Interface()), Secret: secret})
+}
+
+// Sets the config at yaml `path` of `cfg`, like "gateway.databases.main.port",
+// items of slices are addressed by their index like "gateway.listeners.0.name"
+func Set(cfg interface{}, path string, raw string) error {
+	value := reflect.ValueOf(cfg)
+	if value.Kind() != reflect.Ptr || value.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("config: cfg has to be a pointer to struct")
+	}
+	if err := setPath(value.Elem(), strings.Split(path, "."), raw); err != nil {
+		return fmt.Errorf("config: %s: %w", path, err)
+	}
+	return nil
+}
+
+func setPath(value reflect.Value, parts []string, raw string) error {
+	if len(parts) == 0 {
+		return setValue(value, raw)
+	}
+
+	switch value.Kind() {
+	case reflect.Ptr:
+		if value.IsNil() {
+			value.Set(reflect.New(value.Type().Elem()))
+		}
+		return setPath(value.Elem(), parts, raw)
+	case reflect.Struct:
+		for i := 0; i < value.NumField(); i++ {
+			if fieldName(value.Type().Field(i)) == parts[0] {
+				return setPath(value.Field(i), parts[1:], raw)
+			}
+		}
+		return fmt.Errorf("unknown config `%s`", parts[0])
+	case reflect.Map:
+		if value.IsNil() {
+			value.Set(reflect.MakeMap(value.Type()))
+		}
+		key := reflect.ValueOf(parts[0]).Convert(value.Type().Key())
+		elem := reflect.New(value.Type().Elem()).Elem()
+		if existing := value.MapIndex(key); existing.IsValid() {
+			elem.Set(existing)
+		}
+		if err := setPath(elem, parts[1:], raw); err != nil {
+			return err
+		}
+		value.SetMapIndex(key, elem)
+		return nil
+	case reflect.Slice:
+		index, err := strconv.Atoi(parts[0])
+		if err != nil || index < 0 {
+			return fmt.Errorf("`%s` is not a valid index", parts[0])
+		}
+		for value.Len() <= index {
+			value.Set(reflect.Append(value, reflect.New(value.Type().Elem()).Elem()))
+		}
+		return setPath(value.Index(index), parts[1:], raw)
+	}
+	return fmt.Errorf("`%s` has no field `%s`", value.Type(), parts[0])
+}
@@ -0,0 +1,47 @@
+package config
+
+import (
+	"sync"
+	"sync/atomic"
+)
+
+// Holds current configs, so that they can get swapped atomically
+// on hot reload while they are being read
+type Store[T any] struct {
+	current atomic.Pointer[T]
+
+	lock        sync.Mutex
+	subscribers []func(old, new *T)
+}
+
+// Returns a new Store holding `cfg`
+func NewStore[T any](cfg *T) *Store[T] {
+	s := &Store[T]{}
+	s.current.Store(cfg)
+	return s
+}
+
+// Returns current configs, never change the returned value
+func (s *Store[T]) Load() *T {
+	return s.current.Load()
+}
+
+// Replaces current configs and notifies subscribers
+func (s *Store[T]) Swap(cfg *T) {
+	old := s.current.Swap(cfg)
+
+	s.lock.Lock()
+	subscribers := append([]func(old, new *T){}, s.subscribers...)
+	s.lock.Unlock()
+
+	for _, subscriber := range subscribers {
+		subscriber(old, cfg)
+	}
+}
+
+// Registers a function to call after every Swap
+func (s *Store[T]) Subscribe(subscriber func(old, new *T)) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.subscribers = append(s.subscribers, subscriber)
+}
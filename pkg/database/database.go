@@ -17,7 +17,7 @@ type (
 	Database struct {
 		Type     string `yaml:"type"`
 		Username string `yaml:"username"`
-		Password string `yaml:"password"`
+		Password string `yaml:"password" secret:"+"`
 		DbName   string `yaml:"db_name"`
 		Host     string `yaml:"host"`
 		Port     string `yaml:"port"`
@@ -29,6 +29,19 @@ type (
 	RelationalDatabaseFunction func() (*sql.DB, error)
 )
 
+// Database types which can be used in configs
+var SupportedTypes = []string{"mysql", "sqlite3", "postgres", "mssql"}
+
+// Reports if passed database type is supported
+func IsSupported(dbType string) bool {
+	for _, supported := range SupportedTypes {
+		if strings.ToLower(dbType) == supported {
+			return true
+		}
+	}
+	return false
+}
+
 // creates connections and returns connections and main or test database error if anything wrong happened
 func New(dbs map[string]Database, debug bool) (cons map[string]RelationalDatabaseFunction, db RelationalDatabaseFunction, err error) {
 	cons = map[string]RelationalDatabaseFunction{}
@@ -53,7 +66,7 @@ func New(dbs map[string]Database, debug bool) (cons map[string]RelationalDatabas
 
 		switch strings.ToLower(v.Type) {
 		case "mysql":
-			config = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s", v.Username, v.Password, v.Host, v.Port, v.DbName)
+			config = fmt.Sprintf("%s:%s@tcp(%s:%s)/%s?parseTime=true", v.Username, v.Password, v.Host, v.Port, v.DbName)
 		case "sqlite3":
 			if _, err = os.Stat(v.DbName); err != nil {
 				_, err = os.Create(v.DbName)
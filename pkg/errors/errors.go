@@ -39,6 +39,8 @@ const (
 	ServiceUnavailable
 	// TooManyRequests 429
 	TooManyRequests
+	// Conflict 409
+	ConflictStatus
 )
 
 var (
@@ -52,6 +54,7 @@ var (
 		TimeoutStatus:          http.StatusRequestTimeout,
 		ServiceUnavailable:     http.StatusServiceUnavailable,
 		TooManyRequests:        http.StatusTooManyRequests,
+		ConflictStatus:         http.StatusConflict,
 	}
 )
 
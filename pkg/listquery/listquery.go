@@ -0,0 +1,265 @@
+// Parses query parameters of list endpoints into filters, sorts and fields
+// of a query, only what the whitelist of the model allows gets accepted
+//
+//	?filter[is_active]=true&filter[created_at][gte]=2023-01-01T00:00:00Z&sort=-created_at,id&fields=id,display_name
+package listquery
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"service/pkg/repositories"
+)
+
+// Operators of filters, `filter[column]=value` means eq
+const (
+	Eq      = "eq"
+	Ne      = "ne"
+	Gt      = "gt"
+	Gte     = "gte"
+	Lt      = "lt"
+	Lte     = "lte"
+	Like    = "like"
+	In      = "in"
+	Between = "between"
+	Null    = "null"
+)
+
+// Whitelist of a model which list endpoints accept
+type Spec struct {
+	// A value of the model, types of filter values come from its fields
+	Model any
+	// Operators of columns which can be filtered
+	Filters map[string][]string
+	// Columns which can be sorted
+	Sorts []string
+	// Json keys which can be selected, nothing => fields param is not accepted
+	Fields []string
+	// Sort which gets used when sort param is empty
+	DefaultSort []repositories.Sort
+}
+
+// Parsed list query
+type Query struct {
+	// Filters and-ed together, nil if there is no filter
+	Where repositories.Expr
+	Sort  []repositories.Sort
+	// Selected json keys, empty => all of them
+	Fields []string
+}
+
+// Errors of parameters by their name, like `filter[id][gt]`
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	messages := []string{}
+	for param, message := range e {
+		messages = append(messages, param+": "+message)
+	}
+	return "listquery: " + strings.Join(messages, ", ")
+}
+
+var filterParam = regexp.MustCompile(`^filter\[([^\]]+)\](?:\[([^\]]+)\])?$`)
+
+// Parses `values` against `spec`, invalid parameters return as Errors
+func Parse(values url.Values, spec Spec) (*Query, error) {
+	errs := Errors{}
+	query := &Query{}
+	fieldTypes := columnTypes(spec.Model)
+
+	filters := []repositories.Expr{}
+	for param, vals := range values {
+		match := filterParam.FindStringSubmatch(param)
+		if match == nil {
+			continue
+		}
+		column, operator := match[1], match[2]
+		if operator == "" {
+			operator = Eq
+		}
+		if !contains(spec.Filters[column], operator) {
+			errs[param] = fmt.Sprintf("filtering `%s` by `%s` is not allowed", column, operator)
+			continue
+		}
+		filter, err := newFilter(column, operator, vals[len(vals)-1], fieldTypes[column])
+		if err != nil {
+			errs[param] = err.Error()
+			continue
+		}
+		filters = append(filters, filter)
+	}
+	if len(filters) > 0 {
+		query.Where = repositories.And(filters...)
+	}
+
+	query.Sort = spec.DefaultSort
+	if sort := strings.TrimSpace(values.Get("sort")); sort != "" {
+		query.Sort = []repositories.Sort{}
+		for _, column := range strings.Split(sort, ",") {
+			column = strings.TrimSpace(column)
+			desc := strings.HasPrefix(column, "-")
+			column = strings.TrimPrefix(column, "-")
+			if !contains(spec.Sorts, column) {
+				errs["sort"] = fmt.Sprintf("sorting by `%s` is not allowed", column)
+				break
+			}
+			query.Sort = append(query.Sort, repositories.Sort{Column: column, Desc: desc})
+		}
+	}
+
+	if fields := strings.TrimSpace(values.Get("fields")); fields != "" {
+		for _, field := range strings.Split(fields, ",") {
+			field = strings.TrimSpace(field)
+			if !contains(spec.Fields, field) {
+				errs["fields"] = fmt.Sprintf("selecting `%s` is not allowed", field)
+				break
+			}
+			query.Fields = append(query.Fields, field)
+		}
+	}
+
+	if len(errs) > 0 {
+		return nil, errs
+	}
+	return query, nil
+}
+
+func newFilter(column string, operator string, raw string, fieldType reflect.Type) (repositories.Expr, error) {
+	switch operator {
+	case Null:
+		isNull, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("`%s` is not a boolean", raw)
+		}
+		if isNull {
+			return repositories.IsNull(column), nil
+		}
+		return repositories.IsNotNull(column), nil
+	case Like:
+		return repositories.Like(column, "%"+raw+"%"), nil
+	case In, Between:
+		parts := strings.Split(raw, ",")
+		if operator == Between && len(parts) != 2 {
+			return nil, fmt.Errorf("between needs two values like `from,to`")
+		}
+		values := make([]any, len(parts))
+		for i, part := range parts {
+			value, err := convert(strings.TrimSpace(part), fieldType)
+			if err != nil {
+				return nil, err
+			}
+			values[i] = value
+		}
+		if operator == Between {
+			return repositories.Between(column, values[0], values[1]), nil
+		}
+		return repositories.In(column, values...), nil
+	}
+
+	value, err := convert(raw, fieldType)
+	if err != nil {
+		return nil, err
+	}
+	switch operator {
+	case Ne:
+		return repositories.Ne(column, value), nil
+	case Gt:
+		return repositories.Gt(column, value), nil
+	case Gte:
+		return repositories.Gte(column, value), nil
+	case Lt:
+		return repositories.Lt(column, value), nil
+	case Lte:
+		return repositories.Lte(column, value), nil
+	}
+	return repositories.Eq(column, value), nil
+}
+
+// Converts `raw` to type of the field, times have to be like RFC3339
+func convert(raw string, fieldType reflect.Type) (any, error) {
+	if fieldType == nil {
+		return raw, nil
+	}
+	for fieldType.Kind() == reflect.Ptr {
+		fieldType = fieldType.Elem()
+	}
+	switch {
+	case fieldType == reflect.TypeOf(time.Time{}):
+		value, err := time.Parse(time.RFC3339, raw)
+		if err != nil {
+			return nil, fmt.Errorf("`%s` has to be like %s", raw, time.RFC3339)
+		}
+		return value, nil
+	case fieldType.Kind() == reflect.Bool:
+		value, err := strconv.ParseBool(raw)
+		if err != nil {
+			return nil, fmt.Errorf("`%s` is not a boolean", raw)
+		}
+		return value, nil
+	case fieldType.Kind() == reflect.String:
+		return raw, nil
+	}
+
+	// numbers get decoded like json to respect their kind
+	value := reflect.New(fieldType)
+	if err := json.Unmarshal([]byte(raw), value.Interface()); err != nil {
+		return nil, fmt.Errorf("`%s` is not a valid %s", raw, fieldType.Kind())
+	}
+	return value.Elem().Interface(), nil
+}
+
+// Returns types of fields of the model by their column
+func columnTypes(model any) map[string]reflect.Type {
+	types := map[string]reflect.Type{}
+	if model == nil {
+		return types
+	}
+	dataType := reflect.TypeOf(model)
+	for dataType.Kind() == reflect.Ptr {
+		dataType = dataType.Elem()
+	}
+	for _, f := range reflect.VisibleFields(dataType) {
+		if name := f.Tag.Get("db"); f.IsExported() && name != "-" && name != "" {
+			types[name] = f.Type
+		}
+	}
+	return types
+}
+
+// Keeps only `fields` (json keys) of rows, rows are kept if fields is empty
+func (q *Query) Project(rows any) (any, error) {
+	if len(q.Fields) == 0 {
+		return rows, nil
+	}
+	content, err := json.Marshal(rows)
+	if err != nil {
+		return nil, err
+	}
+	all := []map[string]any{}
+	if err := json.Unmarshal(content, &all); err != nil {
+		return nil, err
+	}
+	projected := make([]map[string]any, len(all))
+	for i, row := range all {
+		projected[i] = make(map[string]any, len(q.Fields))
+		for _, field := range q.Fields {
+			projected[i][field] = row[field]
+		}
+	}
+	return projected, nil
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,64 @@
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+)
+
+// Name of the lock which migrations run under
+const lockName = "service_migrations"
+
+// Key of postgres advisory lock, postgres locks are identified by numbers
+const postgresLockKey int64 = 0x6d6967726174696f
+
+// Sets the file which is locked while sqlite migrations run, other
+// databases use their own locks and don't need it
+func (m *Migrations) SetLockFile(path string) {
+	m.lockFile = path
+}
+
+// Waits until no other process (clone or replica) runs migrations and
+// locks them, calling the returned function releases the lock
+func (m *Migrations) lock(ctx context.Context, db *sql.DB) (func(), error) {
+	switch m.dialect {
+	case "sqlite3":
+		if m.lockFile == "" {
+			return func() {}, nil
+		}
+		return lockFile(m.lockFile)
+	case "postgres":
+		return lockSession(ctx, db,
+			fmt.Sprintf("SELECT pg_advisory_lock(%d)", postgresLockKey),
+			fmt.Sprintf("SELECT pg_advisory_unlock(%d)", postgresLockKey),
+		)
+	case "mysql":
+		return lockSession(ctx, db,
+			fmt.Sprintf("SELECT GET_LOCK(%s, -1)", quote(lockName)),
+			fmt.Sprintf("SELECT RELEASE_LOCK(%s)", quote(lockName)),
+		)
+	case "mssql":
+		return lockSession(ctx, db,
+			fmt.Sprintf("EXEC sp_getapplock @Resource = %s, @LockMode = 'Exclusive', @LockOwner = 'Session', @LockTimeout = -1", quote(lockName)),
+			fmt.Sprintf("EXEC sp_releaseapplock @Resource = %s, @LockOwner = 'Session'", quote(lockName)),
+		)
+	}
+	return func() {}, nil
+}
+
+// Database locks belong to the session, so lock and unlock have to run
+// on the same connection which stays open while migrations run
+func lockSession(ctx context.Context, db *sql.DB, lock string, unlock string) (func(), error) {
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := conn.ExecContext(ctx, lock); err != nil {
+		conn.Close()
+		return nil, fmt.Errorf("migrations: failed to lock: %w", err)
+	}
+	return func() {
+		conn.ExecContext(context.Background(), unlock)
+		conn.Close()
+	}, nil
+}
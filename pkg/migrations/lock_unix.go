@@ -0,0 +1,25 @@
+//go:build !windows
+
+package migrations
+
+import (
+	"fmt"
+	"os"
+	"syscall"
+)
+
+// Locks `path` exclusively, waits if another process holds the lock
+func lockFile(path string) (func(), error) {
+	file, err := os.OpenFile(path, os.O_CREATE|os.O_RDWR, 0644)
+	if err != nil {
+		return nil, err
+	}
+	if err := syscall.Flock(int(file.Fd()), syscall.LOCK_EX); err != nil {
+		file.Close()
+		return nil, fmt.Errorf("migrations: failed to lock %s: %w", path, err)
+	}
+	return func() {
+		syscall.Flock(int(file.Fd()), syscall.LOCK_UN)
+		file.Close()
+	}, nil
+}
@@ -0,0 +1,210 @@
+package migrations
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/fs"
+	"net/http"
+	"sort"
+	"strings"
+
+	migrate "github.com/rubenv/sql-migrate"
+)
+
+// Table which keeps checksum of applied migrations
+const ChecksumTable = "migration_checksums"
+
+var (
+	// An error which returns when there is no migration for the database type
+	ErrUnsupportedDialect error = errors.New("no migrations for this database type")
+)
+
+// An error which returns when applied migrations got edited afterwards
+type ChecksumError struct {
+	Ids []string
+}
+
+func (e *ChecksumError) Error() string {
+	return fmt.Sprintf("migrations: applied migrations got edited, revert them and add new migrations instead: %s", strings.Join(e.Ids, ", "))
+}
+
+// Migrations of a database type (dialect)
+type Migrations struct {
+	fsys     fs.FS
+	dialect  string
+	lockFile string
+}
+
+// Returns migrations of `dialect` which are in `root/<dialect>` folder of `fsys`
+func New(fsys fs.FS, root string, dialect string) (*Migrations, error) {
+	sub, err := fs.Sub(fsys, root+"/"+dialect)
+	if err != nil {
+		return nil, err
+	}
+	if _, err := fs.ReadDir(sub, "."); err != nil {
+		return nil, fmt.Errorf("migrations: %s: %w", dialect, ErrUnsupportedDialect)
+	}
+	return &Migrations{fsys: sub, dialect: dialect}, nil
+}
+
+// Returns dialect of migrations, which is also the sql-migrate dialect
+func (m *Migrations) Dialect() string {
+	return m.dialect
+}
+
+// Returns sql-migrate source of migrations
+func (m *Migrations) Source() migrate.MigrationSource {
+	return migrate.HttpFileSystemMigrationSource{FileSystem: http.FS(m.fsys)}
+}
+
+// Verifies checksums, runs at most `max` migrations (0 => all) in
+// `direction` and records checksums of the applied ones
+//
+// Everything runs under a lock, so processes which start together
+// apply every migration just once
+func (m *Migrations) Exec(db *sql.DB, direction migrate.MigrationDirection, max int) (int, error) {
+	unlock, err := m.lock(context.Background(), db)
+	if err != nil {
+		return 0, err
+	}
+	defer unlock()
+
+	if err := m.Verify(db); err != nil {
+		return 0, err
+	}
+	n, err := migrate.ExecMax(db, m.dialect, m.Source(), direction, max)
+	if err != nil {
+		return n, err
+	}
+	return n, m.record(db)
+}
+
+// Returns count of migrations which are not applied yet
+func (m *Migrations) Pending(db *sql.DB) (int, error) {
+	planned, _, err := migrate.PlanMigration(db, m.dialect, m.Source(), migrate.Up, 0)
+	return len(planned), err
+}
+
+// Returns records of applied migrations
+func (m *Migrations) Applied(db *sql.DB) ([]*migrate.MigrationRecord, error) {
+	return migrate.GetMigrationRecords(db, m.dialect)
+}
+
+// Reports applied migrations whose files changed since they got applied
+// as ChecksumError
+func (m *Migrations) Verify(db *sql.DB) error {
+	stored, err := m.stored(db)
+	if err != nil {
+		return err
+	}
+	checksums, err := m.checksums()
+	if err != nil {
+		return err
+	}
+
+	edited := []string{}
+	for id, checksum := range stored {
+		if current, ok := checksums[id]; ok && current != checksum {
+			edited = append(edited, id)
+		}
+	}
+	if len(edited) == 0 {
+		return nil
+	}
+	sort.Strings(edited)
+	return &ChecksumError{Ids: edited}
+}
+
+// Returns checksum of migration files by their id
+func (m *Migrations) checksums() (map[string]string, error) {
+	entries, err := fs.ReadDir(m.fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+	checksums := make(map[string]string, len(entries))
+	for _, entry := range entries {
+		if entry.IsDir() || !strings.HasSuffix(entry.Name(), ".sql") {
+			continue
+		}
+		content, err := fs.ReadFile(m.fsys, entry.Name())
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(content)
+		checksums[entry.Name()] = hex.EncodeToString(sum[:])
+	}
+	return checksums, nil
+}
+
+// Creates checksum table if it doesn't exist and returns stored checksums
+func (m *Migrations) stored(db *sql.DB) (map[string]string, error) {
+	create := fmt.Sprintf("CREATE TABLE IF NOT EXISTS %s (id VARCHAR(255) NOT NULL PRIMARY KEY, checksum VARCHAR(64) NOT NULL)", ChecksumTable)
+	if m.dialect == "mssql" {
+		create = fmt.Sprintf("IF OBJECT_ID(N'%s', N'U') IS NULL CREATE TABLE %s (id NVARCHAR(255) NOT NULL PRIMARY KEY, checksum NVARCHAR(64) NOT NULL)", ChecksumTable, ChecksumTable)
+	}
+	if _, err := db.Exec(create); err != nil {
+		return nil, err
+	}
+
+	rows, err := db.Query(fmt.Sprintf("SELECT id, checksum FROM %s", ChecksumTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := map[string]string{}
+	for rows.Next() {
+		var id, checksum string
+		if err := rows.Scan(&id, &checksum); err != nil {
+			return nil, err
+		}
+		stored[id] = checksum
+	}
+	return stored, rows.Err()
+}
+
+// Stores checksum of newly applied migrations and removes checksum of
+// the rolled back ones
+func (m *Migrations) record(db *sql.DB) error {
+	stored, err := m.stored(db)
+	if err != nil {
+		return err
+	}
+	checksums, err := m.checksums()
+	if err != nil {
+		return err
+	}
+	records, err := m.Applied(db)
+	if err != nil {
+		return err
+	}
+
+	applied := make(map[string]bool, len(records))
+	for _, record := range records {
+		applied[record.Id] = true
+		checksum, ok := checksums[record.Id]
+		if _, recorded := stored[record.Id]; recorded || !ok {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("INSERT INTO %s (id, checksum) VALUES (%s, %s)", ChecksumTable, quote(record.Id), quote(checksum))); err != nil {
+			return err
+		}
+	}
+	for id := range stored {
+		if applied[id] {
+			continue
+		}
+		if _, err := db.Exec(fmt.Sprintf("DELETE FROM %s WHERE id = %s", ChecksumTable, quote(id))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
@@ -0,0 +1,219 @@
+package repositories
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	rawErrors "errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var (
+	// An error which returns when a cursor is malformed, its signature
+	// doesn't match or it belongs to another ordering
+	ErrInvalidCursor error = rawErrors.New("repositories: invalid cursor")
+)
+
+// Position of a row in an ordered list, value of the sort column and id
+// of the row since rows with equal values get ordered by id
+type Cursor struct {
+	OrderBy string          `json:"o"`
+	Desc    bool            `json:"d"`
+	Value   json.RawMessage `json:"v"`
+	Id      int64           `json:"i"`
+	// Reports if the page is before the row, otherwise it is after the row
+	Before bool `json:"b"`
+}
+
+// Returns an opaque token of the cursor which is signed by `key`
+func EncodeCursor(cursor Cursor, key []byte) string {
+	payload, _ := json.Marshal(cursor)
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	return base64.RawURLEncoding.EncodeToString(payload) + "." + base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// Returns cursor of a token which EncodeCursor made, ErrInvalidCursor if
+// the token isn't signed by `key`
+func DecodeCursor(token string, key []byte) (Cursor, error) {
+	cursor := Cursor{}
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok {
+		return cursor, ErrInvalidCursor
+	}
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	sum, err := base64.RawURLEncoding.DecodeString(signature)
+	if err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	mac := hmac.New(sha256.New, key)
+	mac.Write(payload)
+	if !hmac.Equal(sum, mac.Sum(nil)) {
+		return cursor, ErrInvalidCursor
+	}
+	if err := json.Unmarshal(payload, &cursor); err != nil {
+		return cursor, ErrInvalidCursor
+	}
+	return cursor, nil
+}
+
+// Options of FindPage
+type CursorOptions struct {
+	// Column which rows get ordered by, id by default, it shouldn't be NULL
+	OrderBy string
+	Desc    bool
+	// Count of rows of a page
+	Limit int
+	// Token of the page, empty => the first page
+	Cursor string
+	// Key which cursors get signed by
+	Key []byte
+	// Relations which get loaded on found rows, see Preload
+	Preload []string
+}
+
+// A page of rows and cursors of its neighbour pages, cursors are empty
+// if there is no page in that direction
+type CursorPage[T any] struct {
+	Rows []T
+	Next string
+	Prev string
+}
+
+// Returns a page of rows which match `where` (nil => all rows) ordered by
+// keys of rows instead of offsets, so pages don't shift when rows get
+// inserted and no count of rows is needed
+func (r *Repository[T]) FindPage(ctx context.Context, where Expr, options CursorOptions) (*CursorPage[T], error) {
+	if options.OrderBy == "" {
+		options.OrderBy = "id"
+	}
+	if !r.meta.columnSet[options.OrderBy] || !r.meta.columnSet["id"] {
+		return nil, fmt.Errorf("repositories: `%s` is not a column of `%s`", options.OrderBy, r.meta.table)
+	}
+	if options.Limit <= 0 {
+		return nil, fmt.Errorf("repositories: limit of a page has to be positive")
+	}
+
+	// rows after the cursor get read in the order, rows before it in reverse
+	cursor := Cursor{OrderBy: options.OrderBy, Desc: options.Desc}
+	hasCursor := options.Cursor != ""
+	if hasCursor {
+		var err error
+		if cursor, err = DecodeCursor(options.Cursor, options.Key); err != nil {
+			return nil, err
+		}
+		if cursor.OrderBy != options.OrderBy || cursor.Desc != options.Desc {
+			return nil, ErrInvalidCursor
+		}
+	}
+	desc := options.Desc != cursor.Before
+
+	conditions := []Expr{}
+	if where != nil {
+		conditions = append(conditions, where)
+	}
+	if hasCursor {
+		keyset, err := r.keyset(cursor, desc)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, keyset)
+	}
+
+	q := r.query(reflect.New(r.rowType).Interface())
+	wheres := ""
+	if len(conditions) > 0 {
+		var err error
+		if wheres, err = q.exprWheres(And(conditions...)); err != nil {
+			return nil, err
+		}
+	}
+	q.SelectWhere(wheres)
+	direction := "ASC"
+	if desc {
+		direction = "DESC"
+	}
+	if options.OrderBy == "id" {
+		q.query += fmt.Sprintf(" ORDER BY id %s", direction)
+	} else {
+		q.query += fmt.Sprintf(" ORDER BY %s %s, id %s", options.OrderBy, direction, direction)
+	}
+	q.query = r.dialect.Paginate(q.query, options.Limit+1, 0)
+
+	rows := []T{}
+	if err := q.ExecQueryMultiErr(ctx, r.db, &rows); err != nil {
+		return nil, err
+	}
+	hasMore := len(rows) > options.Limit
+	if hasMore {
+		rows = rows[:options.Limit]
+	}
+	if cursor.Before {
+		for i, j := 0, len(rows)-1; i < j; i, j = i+1, j-1 {
+			rows[i], rows[j] = rows[j], rows[i]
+		}
+	}
+	for _, row := range rows {
+		r.bind(reflect.ValueOf(row))
+	}
+	if err := r.Preload(ctx, rows, options.Preload...); err != nil {
+		return nil, err
+	}
+
+	page := &CursorPage[T]{Rows: rows}
+	if len(rows) == 0 {
+		return page, nil
+	}
+	// reading forward has a previous page if it started at a cursor and
+	// reading backward always has a next page
+	if (!cursor.Before && hasMore) || cursor.Before {
+		page.Next = r.cursorOf(rows[len(rows)-1], options, false)
+	}
+	if (cursor.Before && hasMore) || (!cursor.Before && hasCursor) {
+		page.Prev = r.cursorOf(rows[0], options, true)
+	}
+	return page, nil
+}
+
+// Returns condition of rows which come after the cursor in `desc` order
+func (r *Repository[T]) keyset(cursor Cursor, desc bool) (Expr, error) {
+	compare := Gt
+	if desc {
+		compare = Lt
+	}
+	if cursor.OrderBy == "id" {
+		return compare("id", cursor.Id), nil
+	}
+
+	// value gets restored to the type of its field, like time.Time
+	field := r.rowType.FieldByIndex(r.meta.fields[cursor.OrderBy])
+	value := reflect.New(field.Type)
+	if err := json.Unmarshal(cursor.Value, value.Interface()); err != nil {
+		return nil, ErrInvalidCursor
+	}
+	return Or(
+		compare(cursor.OrderBy, value.Elem().Interface()),
+		And(Eq(cursor.OrderBy, value.Elem().Interface()), compare("id", cursor.Id)),
+	), nil
+}
+
+func (r *Repository[T]) cursorOf(row T, options CursorOptions, before bool) string {
+	value := reflect.ValueOf(row).Elem()
+	cursor := Cursor{
+		OrderBy: options.OrderBy,
+		Desc:    options.Desc,
+		Id:      value.FieldByIndex(r.meta.fields["id"]).Int(),
+		Before:  before,
+	}
+	if options.OrderBy != "id" {
+		cursor.Value, _ = json.Marshal(value.FieldByIndex(r.meta.fields[options.OrderBy]).Interface())
+	}
+	return EncodeCursor(cursor, options.Key)
+}
@@ -0,0 +1,372 @@
+package repositories
+
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
+// Differences of databases which generated queries have to respect
+type Dialect interface {
+	// Returns database type of the dialect, like postgres
+	Name() string
+	// Quotes an identifier like a table or column name
+	Quote(identifier string) string
+	// Returns placeholder of the n-th (starting from 1) bound parameter
+	Placeholder(n int) string
+	// Returns literal of a boolean
+	Bool(value bool) string
+	// Returns literal of a time
+	Time(value time.Time) string
+	// Returns literal of a string with escaped characters
+	String(value string) string
+	// Converts a bound parameter to the form which literals of the value have
+	Arg(value any) any
+	// Adds pagination into a select query
+	Paginate(query string, limit, offset int) string
+	// Returns a case-insensitive LIKE expression
+	Like(column string, pattern string) string
+	// Returns an insert statement which returns `returning` columns of the
+	// inserted rows if the database supports it, `values` is like "(1, 2), (3, 4)"
+	Insert(table string, columns string, values string, returning []string) string
+	// Reports if inserts return rows or inserted id has to be read by LastInsertId
+	CanReturn() bool
+	// Reports if an insert of many rows returns them in order of its values,
+	// otherwise every returning insert gets a single row
+	ReturnsInOrder() bool
+	// Returns an insert statement of one row which applies `sets` to the
+	// existing row instead if it conflicts on `conflict` columns, the
+	// existing row gets returned like an inserted one
+	//
+	// mysql can't choose the key, `conflict` gets ignored and `sets` get
+	// applied on a conflict of any unique key
+	Upsert(table string, columns string, values string, conflict []string, sets []string, returning []string) string
+	// Returns reference of a column of the row which an upsert tried to insert
+	Excluded(column string) string
+	// Returns max count of rows and max length in bytes of an insert
+	// statement, values are written into statements as literals so limits
+	// are on rows of VALUES and length of statements, 0 => no limit
+	BatchSize() (rows int, length int)
+}
+
+var dialects = map[string]Dialect{
+	"postgres": postgresDialect{},
+	"sqlite3":  sqliteDialect{},
+	"mysql":    mysqlDialect{},
+	"mssql":    mssqlDialect{},
+}
+
+// Returns dialect of `dbType`, unknown types get an ANSI SQL dialect
+func GetDialect(dbType string) Dialect {
+	if dialect, ok := dialects[strings.ToLower(dbType)]; ok {
+		return dialect
+	}
+	return ansiDialect{}
+}
+
+// Standard SQL, other dialects override what they do differently
+type ansiDialect struct{}
+
+func (ansiDialect) Name() string {
+	return ""
+}
+
+func (ansiDialect) Quote(identifier string) string {
+	return `"` + strings.ReplaceAll(identifier, `"`, `""`) + `"`
+}
+
+func (ansiDialect) Placeholder(n int) string {
+	return "?"
+}
+
+func (ansiDialect) Bool(value bool) string {
+	if value {
+		return "TRUE"
+	}
+	return "FALSE"
+}
+
+func (ansiDialect) Time(value time.Time) string {
+	return fmt.Sprintf("'%s'", value.UTC().Format(time.RFC3339Nano))
+}
+
+func (ansiDialect) String(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+func (ansiDialect) Arg(value any) any {
+	return value
+}
+
+func (ansiDialect) Paginate(query string, limit, offset int) string {
+	return fmt.Sprintf("%s LIMIT %d OFFSET %d", query, limit, offset)
+}
+
+func (ansiDialect) Like(column string, pattern string) string {
+	return fmt.Sprintf("LOWER(%s) LIKE LOWER(%s)", column, pattern)
+}
+
+func (ansiDialect) Insert(table string, columns string, values string, returning []string) string {
+	return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, columns, values)
+}
+
+func (ansiDialect) CanReturn() bool {
+	return false
+}
+
+func (ansiDialect) ReturnsInOrder() bool {
+	return true
+}
+
+func (ansiDialect) Upsert(table string, columns string, values string, conflict []string, sets []string, returning []string) string {
+	return conflictInsert(table, columns, values, conflict, sets, returning)
+}
+
+func (ansiDialect) Excluded(column string) string {
+	return "EXCLUDED." + column
+}
+
+// Unknown databases get statements which most databases accept
+func (ansiDialect) BatchSize() (int, int) {
+	return 1000, 1 << 20
+}
+
+type postgresDialect struct {
+	ansiDialect
+}
+
+func (postgresDialect) Name() string {
+	return "postgres"
+}
+
+func (postgresDialect) Placeholder(n int) string {
+	return fmt.Sprintf("$%d", n)
+}
+
+func (postgresDialect) Like(column string, pattern string) string {
+	return fmt.Sprintf("%s ILIKE %s", column, pattern)
+}
+
+func (postgresDialect) Insert(table string, columns string, values string, returning []string) string {
+	return returningInsert(table, columns, values, returning)
+}
+
+func (postgresDialect) CanReturn() bool {
+	return true
+}
+
+// postgres only limits statements to 1GB, smaller ones keep memory low
+func (postgresDialect) BatchSize() (int, int) {
+	return 0, 16 << 20
+}
+
+// sqlite supports RETURNING since 3.35
+type sqliteDialect struct {
+	ansiDialect
+}
+
+func (sqliteDialect) Name() string {
+	return "sqlite3"
+}
+
+// Layout of sqlite times, sqlite keeps times as text which only get
+// ordered right if all of them have the same width, RFC3339Nano drops
+// trailing zeros of fractions
+const sqliteTimeLayout = "2006-01-02T15:04:05.000000000Z"
+
+func (sqliteDialect) Time(value time.Time) string {
+	return fmt.Sprintf("'%s'", value.UTC().Format(sqliteTimeLayout))
+}
+
+// Bound times have to be formatted like their literals to be comparable,
+// the driver formats them differently
+func (sqliteDialect) Arg(value any) any {
+	if t, ok := value.(time.Time); ok {
+		return t.UTC().Format(sqliteTimeLayout)
+	}
+	return value
+}
+
+// LIKE of sqlite is case-insensitive for ASCII characters
+func (sqliteDialect) Like(column string, pattern string) string {
+	return fmt.Sprintf("%s LIKE %s", column, pattern)
+}
+
+func (sqliteDialect) Insert(table string, columns string, values string, returning []string) string {
+	return returningInsert(table, columns, values, returning)
+}
+
+func (sqliteDialect) CanReturn() bool {
+	return true
+}
+
+// sqlite limits statements to 1000000 bytes by default (SQLITE_MAX_SQL_LENGTH)
+func (sqliteDialect) BatchSize() (int, int) {
+	return 0, 1000000
+}
+
+func returningInsert(table string, columns string, values string, returning []string) string {
+	query := fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, columns, values)
+	if len(returning) > 0 {
+		query += " RETURNING " + strings.Join(returning, ", ")
+	}
+	return query
+}
+
+// ON CONFLICT of postgres and sqlite, which returns the row if it got updated
+func conflictInsert(table string, columns string, values string, conflict []string, sets []string, returning []string) string {
+	query := fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s ON CONFLICT (%s) DO UPDATE SET %s",
+		table, columns, values, strings.Join(conflict, ", "), strings.Join(sets, ", "),
+	)
+	if len(returning) > 0 {
+		query += " RETURNING " + strings.Join(returning, ", ")
+	}
+	return query
+}
+
+// mysql has no RETURNING, inserted id is read by LastInsertId
+type mysqlDialect struct {
+	ansiDialect
+}
+
+func (mysqlDialect) Name() string {
+	return "mysql"
+}
+
+func (mysqlDialect) Quote(identifier string) string {
+	return "`" + strings.ReplaceAll(identifier, "`", "``") + "`"
+}
+
+func (mysqlDialect) Time(value time.Time) string {
+	return fmt.Sprintf("'%s'", value.UTC().Format("2006-01-02 15:04:05.999999"))
+}
+
+// Backslash is an escape character in mysql strings too
+func (mysqlDialect) String(value string) string {
+	value = strings.ReplaceAll(value, `\`, `\\`)
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// Default collations of mysql are case-insensitive
+func (mysqlDialect) Like(column string, pattern string) string {
+	return fmt.Sprintf("%s LIKE %s", column, pattern)
+}
+
+// mysql conflicts on any unique key, so `conflict` is ignored and a row
+// which collides on another unique key gets updated, id of the existing
+// row gets set as LastInsertId so it gets selected like an inserted one
+func (mysqlDialect) Upsert(table string, columns string, values string, conflict []string, sets []string, returning []string) string {
+	return fmt.Sprintf(
+		"INSERT INTO %s (%s) VALUES %s ON DUPLICATE KEY UPDATE %s, id = LAST_INSERT_ID(id)",
+		table, columns, values, strings.Join(sets, ", "),
+	)
+}
+
+func (mysqlDialect) Excluded(column string) string {
+	return fmt.Sprintf("VALUES(%s)", column)
+}
+
+// mysql limits statements to max_allowed_packet which is 4MB before 8.0
+func (mysqlDialect) BatchSize() (int, int) {
+	return 0, 4 << 20
+}
+
+type mssqlDialect struct {
+	ansiDialect
+}
+
+func (mssqlDialect) Name() string {
+	return "mssql"
+}
+
+func (mssqlDialect) Quote(identifier string) string {
+	return "[" + strings.ReplaceAll(identifier, "]", "]]") + "]"
+}
+
+func (mssqlDialect) Placeholder(n int) string {
+	return fmt.Sprintf("@p%d", n)
+}
+
+func (mssqlDialect) Bool(value bool) string {
+	if value {
+		return "1"
+	}
+	return "0"
+}
+
+func (mssqlDialect) Time(value time.Time) string {
+	return fmt.Sprintf("'%s'", value.UTC().Format("2006-01-02T15:04:05.9999999"))
+}
+
+func (mssqlDialect) String(value string) string {
+	return "N'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
+
+// OFFSET FETCH needs ORDER BY, so rows get ordered by nothing if query has no order
+func (mssqlDialect) Paginate(query string, limit, offset int) string {
+	if !strings.Contains(strings.ToUpper(query), " ORDER BY ") {
+		query += " ORDER BY (SELECT NULL)"
+	}
+	return fmt.Sprintf("%s OFFSET %d ROWS FETCH NEXT %d ROWS ONLY", query, offset, limit)
+}
+
+// Default collations of mssql are case-insensitive
+func (mssqlDialect) Like(column string, pattern string) string {
+	return fmt.Sprintf("%s LIKE %s", column, pattern)
+}
+
+func (mssqlDialect) Insert(table string, columns string, values string, returning []string) string {
+	if len(returning) == 0 {
+		return fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", table, columns, values)
+	}
+	inserted := make([]string, len(returning))
+	for i, column := range returning {
+		inserted[i] = "INSERTED." + column
+	}
+	return fmt.Sprintf("INSERT INTO %s (%s) OUTPUT %s VALUES %s", table, columns, strings.Join(inserted, ", "), values)
+}
+
+func (mssqlDialect) CanReturn() bool {
+	return true
+}
+
+// OUTPUT doesn't guarantee any order of rows
+func (mssqlDialect) ReturnsInOrder() bool {
+	return false
+}
+
+// MERGE holds the lock of the matched key, so concurrent upserts don't
+// insert the row twice
+func (mssqlDialect) Upsert(table string, columns string, values string, conflict []string, sets []string, returning []string) string {
+	matches := make([]string, len(conflict))
+	for i, column := range conflict {
+		matches[i] = fmt.Sprintf("%s.%s = source.%s", table, column, column)
+	}
+	sources := strings.Split(columns, ", ")
+	for i, column := range sources {
+		sources[i] = "source." + column
+	}
+	query := fmt.Sprintf(
+		"MERGE INTO %s WITH (HOLDLOCK) USING (VALUES %s) AS source (%s) ON %s WHEN MATCHED THEN UPDATE SET %s WHEN NOT MATCHED THEN INSERT (%s) VALUES (%s)",
+		table, values, columns, strings.Join(matches, " AND "), strings.Join(sets, ", "), columns, strings.Join(sources, ", "),
+	)
+	if len(returning) > 0 {
+		inserted := make([]string, len(returning))
+		for i, column := range returning {
+			inserted[i] = "INSERTED." + column
+		}
+		query += " OUTPUT " + strings.Join(inserted, ", ")
+	}
+	// MERGE has to end with a semicolon
+	return query + ";"
+}
+
+func (mssqlDialect) Excluded(column string) string {
+	return "source." + column
+}
+
+// mssql limits rows of VALUES to 1000
+func (mssqlDialect) BatchSize() (int, int) {
+	return 1000, 16 << 20
+}
@@ -0,0 +1,193 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	rawErrors "errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"service/pkg/errors"
+
+	mssql "github.com/denisenkom/go-mssqldb"
+	"github.com/go-sql-driver/mysql"
+	"github.com/lib/pq"
+	"github.com/mattn/go-sqlite3"
+)
+
+var (
+	// An error which returns when no row matches the query
+	ErrNotFound error = rawErrors.New("repositories: row not found")
+	// An error which returns when a row references a missing row or is still referenced
+	ErrForeignKey error = rawErrors.New("repositories: foreign key violation")
+	// An error which returns when the transaction got aborted by a deadlock
+	ErrDeadlock error = rawErrors.New("repositories: deadlock")
+	// An error which returns when the query or a lock of it took too long
+	ErrTimeout error = rawErrors.New("repositories: timeout")
+	// An error which returns when an update of a row with a version field
+	// matched no row, because another update changed the row first
+	ErrStaleRow error = rawErrors.New("repositories: row got changed by another update")
+)
+
+// An error which returns when an insert or update duplicates a unique
+// column, Column is empty if the database didn't tell it
+type ErrUniqueViolation struct {
+	Column string
+	Err    error
+}
+
+func (e *ErrUniqueViolation) Error() string {
+	if e.Column == "" {
+		return "repositories: unique violation: " + e.Err.Error()
+	}
+	return fmt.Sprintf("repositories: unique violation of `%s`: %s", e.Column, e.Err.Error())
+}
+
+func (e *ErrUniqueViolation) Unwrap() error {
+	return e.Err
+}
+
+// Any unique violation matches, so errors.Is(err, &ErrUniqueViolation{}) works
+func (e *ErrUniqueViolation) Is(target error) bool {
+	_, ok := target.(*ErrUniqueViolation)
+	return ok
+}
+
+// An error of the database and its class, errors.Is matches both of them
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string {
+	return e.class.Error() + ": " + e.err.Error()
+}
+
+func (e *classifiedError) Unwrap() []error {
+	return []error{e.class, e.err}
+}
+
+var (
+	pqUniqueKey       = regexp.MustCompile(`Key \(([^)]+)\)=`)
+	sqliteUniqueKey   = regexp.MustCompile(`constraint failed: [^.\s]+\.([^,\s]+)`)
+	mysqlUniqueKey    = regexp.MustCompile(`for key '(?:[^'.]+\.)?([^']+)'`)
+	mssqlUniqueObject = regexp.MustCompile(`(?:constraint|index) '([^']+)'`)
+)
+
+// Maps errors of database drivers (postgres, sqlite3, mysql and mssql) to
+// ErrNotFound, ErrUniqueViolation, ErrForeignKey, ErrDeadlock and
+// ErrTimeout, other errors return as they are
+func Classify(err error) error {
+	if err == nil {
+		return nil
+	}
+	var unique *ErrUniqueViolation
+	var classified *classifiedError
+	if rawErrors.As(err, &unique) || rawErrors.As(err, &classified) {
+		return err
+	}
+
+	class := func(class error) error {
+		return &classifiedError{class: class, err: err}
+	}
+	if rawErrors.Is(err, sql.ErrNoRows) {
+		return class(ErrNotFound)
+	}
+	if rawErrors.Is(err, context.DeadlineExceeded) {
+		return class(ErrTimeout)
+	}
+
+	var pqErr *pq.Error
+	var sqliteErr sqlite3.Error
+	var mysqlErr *mysql.MySQLError
+	var mssqlErr mssql.Error
+	switch {
+	case rawErrors.As(err, &pqErr):
+		switch pqErr.Code {
+		case "23505":
+			return &ErrUniqueViolation{Column: match(pqUniqueKey, pqErr.Detail), Err: err}
+		case "23503":
+			return class(ErrForeignKey)
+		case "40P01":
+			return class(ErrDeadlock)
+		case "57014", "55P03":
+			return class(ErrTimeout)
+		}
+	case rawErrors.As(err, &sqliteErr):
+		switch {
+		case sqliteErr.ExtendedCode == sqlite3.ErrConstraintUnique || sqliteErr.ExtendedCode == sqlite3.ErrConstraintPrimaryKey:
+			return &ErrUniqueViolation{Column: match(sqliteUniqueKey, sqliteErr.Error()), Err: err}
+		case sqliteErr.ExtendedCode == sqlite3.ErrConstraintForeignKey:
+			return class(ErrForeignKey)
+		case sqliteErr.Code == sqlite3.ErrLocked:
+			return class(ErrDeadlock)
+		case sqliteErr.Code == sqlite3.ErrBusy:
+			return class(ErrTimeout)
+		}
+	case rawErrors.As(err, &mysqlErr):
+		switch mysqlErr.Number {
+		case 1062:
+			return &ErrUniqueViolation{Column: match(mysqlUniqueKey, mysqlErr.Message), Err: err}
+		case 1451, 1452:
+			return class(ErrForeignKey)
+		case 1213:
+			return class(ErrDeadlock)
+		case 1205, 3024:
+			return class(ErrTimeout)
+		}
+	case rawErrors.As(err, &mssqlErr):
+		switch mssqlErr.Number {
+		// mssql only tells the constraint, which is the column if it got named after it
+		case 2627, 2601:
+			return &ErrUniqueViolation{Column: match(mssqlUniqueObject, mssqlErr.Message), Err: err}
+		case 547:
+			return class(ErrForeignKey)
+		case 1205:
+			return class(ErrDeadlock)
+		case 1222:
+			return class(ErrTimeout)
+		}
+	}
+	return err
+}
+
+func match(pattern *regexp.Regexp, message string) string {
+	if found := pattern.FindStringSubmatch(message); found != nil {
+		return strings.TrimSpace(found[1])
+	}
+	return ""
+}
+
+// Reports if `err` is one of the errors which Classify maps to
+func IsClassified(err error) bool {
+	var unique *ErrUniqueViolation
+	var classified *classifiedError
+	err = Classify(err)
+	return rawErrors.As(err, &unique) || rawErrors.As(err, &classified)
+}
+
+// Returns a server error of `err` which its status respects the class of
+// `err`, like 404 for ErrNotFound and 409 for ErrUniqueViolation
+func ServerError(err error) error {
+	// errors of hooks can be server errors already
+	if errors.IsServerError(err) {
+		return err
+	}
+	err = Classify(err)
+	switch {
+	case rawErrors.Is(err, ErrNotFound):
+		return errors.New(errors.NotFoundStatus, "NotFound", err.Error())
+	case rawErrors.Is(err, &ErrUniqueViolation{}):
+		return errors.New(errors.ConflictStatus, "AlreadyExists", err.Error())
+	case rawErrors.Is(err, ErrForeignKey):
+		return errors.New(errors.ConflictStatus, "ReferenceConflict", err.Error())
+	case rawErrors.Is(err, ErrStaleRow):
+		return errors.New(errors.ConflictStatus, "StaleRow", err.Error())
+	case rawErrors.Is(err, ErrDeadlock), rawErrors.Is(err, ErrTimeout):
+		return errors.New(errors.ServiceUnavailable, "TryAgainLater", err.Error())
+	case rawErrors.Is(err, ErrInvalidCursor):
+		return errors.New(errors.InvalidStatus, "InvalidCursor", err.Error())
+	}
+	return errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error())
+}
@@ -0,0 +1,315 @@
+package repositories
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// A condition of a where clause, values of conditions get bound as
+// parameters in placeholder style of the query dialect
+//
+//	where := repositories.And(
+//		repositories.Or(
+//			repositories.Like("display_name", "%ali%"),
+//			repositories.Like("email", "%ali%"),
+//		),
+//		repositories.Eq("is_active", true),
+//	)
+//	user.SelectExpr(where).ExecQueryMulti(ctx, db, users)
+type Expr interface {
+	render(r *renderer)
+}
+
+// Renders expressions of a query, columns get validated against db tags
+// of the row which the expression is about
+type renderer struct {
+	dialect Dialect
+	table   string
+	columns map[string]bool
+	args    []any
+	err     error
+	sql     strings.Builder
+}
+
+func newRenderer(dialect Dialect, table string, row any, args []any) *renderer {
+	return &renderer{dialect: dialect, table: table, columns: columnSet(row), args: args}
+}
+
+// Returns `name` if it is a column of the current row, otherwise records an error
+func (r *renderer) column(name string) string {
+	if !r.columns[name] && r.err == nil {
+		r.err = fmt.Errorf("repositories: `%s` is not a column of `%s`", name, r.table)
+	}
+	return name
+}
+
+func (r *renderer) write(sql string) {
+	r.sql.WriteString(sql)
+}
+
+// Binds `value` as a parameter and returns its placeholder
+func (r *renderer) bind(value any) string {
+	r.args = append(r.args, r.dialect.Arg(value))
+	return r.dialect.Placeholder(len(r.args))
+}
+
+// Returns db tags of the visible fields of `row`
+func columnSet(row any) map[string]bool {
+	return metadataOf(reflect.TypeOf(row)).columnSet
+}
+
+type comparison struct {
+	column   string
+	operator string
+	value    any
+}
+
+func (e comparison) render(r *renderer) {
+	column := r.column(e.column)
+	if e.value == nil {
+		switch e.operator {
+		case "=":
+			r.write(column + " IS NULL")
+			return
+		case "<>":
+			r.write(column + " IS NOT NULL")
+			return
+		}
+	}
+	r.write(fmt.Sprintf("%s %s %s", column, e.operator, r.bind(e.value)))
+}
+
+// Column equals value, a nil value checks for NULL
+func Eq(column string, value any) Expr {
+	return comparison{column, "=", value}
+}
+
+// Column doesn't equal value, a nil value checks for NOT NULL
+func Ne(column string, value any) Expr {
+	return comparison{column, "<>", value}
+}
+
+// Column is greater than value
+func Gt(column string, value any) Expr {
+	return comparison{column, ">", value}
+}
+
+// Column is greater than or equal to value
+func Gte(column string, value any) Expr {
+	return comparison{column, ">=", value}
+}
+
+// Column is less than value
+func Lt(column string, value any) Expr {
+	return comparison{column, "<", value}
+}
+
+// Column is less than or equal to value
+func Lte(column string, value any) Expr {
+	return comparison{column, "<=", value}
+}
+
+type in struct {
+	column string
+	values []any
+}
+
+func (e in) render(r *renderer) {
+	column := r.column(e.column)
+	if len(e.values) == 1 {
+		if sub, ok := e.values[0].(*SubqueryExpr); ok {
+			r.write(column + " IN ")
+			sub.render(r)
+			return
+		}
+	}
+
+	placeholders := []string{}
+	for _, value := range e.values {
+		v := reflect.ValueOf(value)
+		if v.Kind() != reflect.Slice || v.Type().Elem().Kind() == reflect.Uint8 {
+			placeholders = append(placeholders, r.bind(value))
+			continue
+		}
+		for i := 0; i < v.Len(); i++ {
+			placeholders = append(placeholders, r.bind(v.Index(i).Interface()))
+		}
+	}
+	// nothing is in an empty list
+	if len(placeholders) == 0 {
+		r.write("1 = 0")
+		return
+	}
+	r.write(fmt.Sprintf("%s IN (%s)", column, strings.Join(placeholders, ", ")))
+}
+
+// Column is one of values, slices get expanded and a single Subquery
+// gets rendered as `column IN (SELECT ...)`
+func In(column string, values ...any) Expr {
+	return in{column, values}
+}
+
+type between struct {
+	column   string
+	from, to any
+}
+
+func (e between) render(r *renderer) {
+	r.write(fmt.Sprintf("%s BETWEEN %s AND %s", r.column(e.column), r.bind(e.from), r.bind(e.to)))
+}
+
+// Column is between from and to, both inclusive
+func Between(column string, from, to any) Expr {
+	return between{column, from, to}
+}
+
+type isNull struct {
+	column string
+	not    bool
+}
+
+func (e isNull) render(r *renderer) {
+	if e.not {
+		r.write(r.column(e.column) + " IS NOT NULL")
+		return
+	}
+	r.write(r.column(e.column) + " IS NULL")
+}
+
+// Column is NULL
+func IsNull(column string) Expr {
+	return isNull{column, false}
+}
+
+// Column is not NULL
+func IsNotNull(column string) Expr {
+	return isNull{column, true}
+}
+
+type like struct {
+	column  string
+	pattern string
+}
+
+func (e like) render(r *renderer) {
+	r.write(r.dialect.Like(r.column(e.column), r.bind(e.pattern)))
+}
+
+// Column matches pattern case-insensitively, like "%ali%"
+func Like(column string, pattern string) Expr {
+	return like{column, pattern}
+}
+
+type logical struct {
+	operator string
+	exprs    []Expr
+}
+
+func (e logical) render(r *renderer) {
+	// empty AND matches everything and empty OR matches nothing
+	if len(e.exprs) == 0 {
+		if e.operator == "AND" {
+			r.write("1 = 1")
+		} else {
+			r.write("1 = 0")
+		}
+		return
+	}
+	r.write("(")
+	for i, expr := range e.exprs {
+		if i > 0 {
+			r.write(" " + e.operator + " ")
+		}
+		expr.render(r)
+	}
+	r.write(")")
+}
+
+// All of exprs are true
+func And(exprs ...Expr) Expr {
+	return logical{"AND", exprs}
+}
+
+// Any of exprs is true
+func Or(exprs ...Expr) Expr {
+	return logical{"OR", exprs}
+}
+
+type not struct {
+	expr Expr
+}
+
+func (e not) render(r *renderer) {
+	r.write("NOT (")
+	e.expr.render(r)
+	r.write(")")
+}
+
+// Expr is false
+func Not(expr Expr) Expr {
+	return not{expr}
+}
+
+// A select of one column of another model, used by In and Exists, deleted
+// rows of a soft deleted model are excluded like other queries
+type SubqueryExpr struct {
+	table  string
+	row    any
+	column string
+	where  Expr
+	scope  scope
+}
+
+func (e *SubqueryExpr) render(r *renderer) {
+	table, columns := r.table, r.columns
+	r.table, r.columns = e.table, columnSet(e.row)
+	r.write(fmt.Sprintf("(SELECT %s FROM %s", r.column(e.column), e.table))
+	condition := (&Query{row: e.row, scope: e.scope}).scoped("", "")
+	if e.where != nil {
+		r.write(" WHERE (")
+		e.where.render(r)
+		r.write(")")
+		if condition != "" {
+			r.write(" AND " + condition)
+		}
+	} else if condition != "" {
+		r.write(" WHERE " + condition)
+	}
+	r.write(")")
+	r.table, r.columns = table, columns
+}
+
+// Makes the subquery select deleted rows of a soft deleted model too
+func (e *SubqueryExpr) WithDeleted() *SubqueryExpr {
+	e.scope = scopeWithDeleted
+	return e
+}
+
+// Makes the subquery only select deleted rows of a soft deleted model
+func (e *SubqueryExpr) OnlyDeleted() *SubqueryExpr {
+	e.scope = scopeOnlyDeleted
+	return e
+}
+
+// Selects `column` of `model` rows which match `where` (nil => all rows),
+// columns of the subquery get validated against `model`
+//
+//	repositories.In("user_id", repositories.Subquery(models.NewUser(), "id", repositories.Eq("is_admin", true)))
+func Subquery(model QueryGenerator, column string, where Expr) *SubqueryExpr {
+	return &SubqueryExpr{table: model.GetTableName(), row: model.GetRowData(), column: column, where: where}
+}
+
+type exists struct {
+	sub *SubqueryExpr
+}
+
+func (e exists) render(r *renderer) {
+	r.write("EXISTS ")
+	e.sub.render(r)
+}
+
+// Subquery returns at least one row
+func Exists(sub *SubqueryExpr) Expr {
+	return exists{sub}
+}
@@ -0,0 +1,135 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	"reflect"
+)
+
+// A database or a transaction which queries get executed on, like *sql.DB and *sql.Tx
+type DB interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+	QueryRowContext(ctx context.Context, query string, args ...any) *sql.Row
+}
+
+// Hooks which models can implement, executors call them with the context
+// and the database or transaction which executes the query. An error of a
+// Before hook aborts the operation before it gets executed, an error of an
+// After hook returns after it, so it only aborts the transaction if there is any
+//
+//	func (u *User) BeforeInsert(ctx context.Context, db repositories.DB) error {
+//		u.HashMyPassword()
+//		return nil
+//	}
+type (
+	BeforeInserter interface {
+		BeforeInsert(ctx context.Context, db DB) error
+	}
+	AfterInserter interface {
+		AfterInsert(ctx context.Context, db DB) error
+	}
+	BeforeUpdater interface {
+		BeforeUpdate(ctx context.Context, db DB) error
+	}
+	AfterUpdater interface {
+		AfterUpdate(ctx context.Context, db DB) error
+	}
+	BeforeDeleter interface {
+		BeforeDelete(ctx context.Context, db DB) error
+	}
+	AfterDeleter interface {
+		AfterDelete(ctx context.Context, db DB) error
+	}
+	// Gets called after the row got loaded by a select
+	AfterLoader interface {
+		AfterLoad(ctx context.Context, db DB) error
+	}
+)
+
+// Operation of current query on its rows which hooks get called for
+type operation int
+
+const (
+	opNone operation = iota
+	opInsert
+	opUpdate
+	opDelete
+)
+
+// Calls Before hooks of `op` on rows, reports if any of them got called
+func beforeHooks(ctx context.Context, db DB, op operation, rows []any) (bool, error) {
+	called := false
+	for _, row := range rows {
+		var err error
+		switch op {
+		case opInsert:
+			if hook, ok := row.(BeforeInserter); ok {
+				called, err = true, hook.BeforeInsert(ctx, db)
+			}
+		case opUpdate:
+			if hook, ok := row.(BeforeUpdater); ok {
+				called, err = true, hook.BeforeUpdate(ctx, db)
+			}
+		case opDelete:
+			if hook, ok := row.(BeforeDeleter); ok {
+				called, err = true, hook.BeforeDelete(ctx, db)
+			}
+		}
+		if err != nil {
+			return called, err
+		}
+	}
+	return called, nil
+}
+
+// Calls After hooks of `op` on rows
+func afterHooks(ctx context.Context, db DB, op operation, rows []any) error {
+	for _, row := range rows {
+		var err error
+		switch op {
+		case opInsert:
+			if hook, ok := row.(AfterInserter); ok {
+				err = hook.AfterInsert(ctx, db)
+			}
+		case opUpdate:
+			if hook, ok := row.(AfterUpdater); ok {
+				err = hook.AfterUpdate(ctx, db)
+			}
+		case opDelete:
+			if hook, ok := row.(AfterDeleter); ok {
+				err = hook.AfterDelete(ctx, db)
+			}
+		}
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Calls AfterLoad hooks of a loaded row or rows of a loaded slice
+func afterLoad(ctx context.Context, db DB, loaded any) error {
+	value := reflect.ValueOf(loaded)
+	for value.Kind() == reflect.Ptr && value.Elem().Kind() != reflect.Struct {
+		value = value.Elem()
+	}
+	if value.Kind() != reflect.Slice {
+		if hook, ok := loaded.(AfterLoader); ok {
+			return hook.AfterLoad(ctx, db)
+		}
+		return nil
+	}
+	for i := 0; i < value.Len(); i++ {
+		row := value.Index(i)
+		if row.Kind() != reflect.Ptr && row.CanAddr() {
+			row = row.Addr()
+		}
+		if hook, ok := row.Interface().(AfterLoader); ok {
+			if err := hook.AfterLoad(ctx, db); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,114 @@
+package repositories
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+)
+
+// Models which define their table name, otherwise plural snake case of
+// the type name gets used, like `users` for `User`
+type Tabler interface {
+	TableName() string
+}
+
+// Table information of a model type, derived once per type
+type metadata struct {
+	table string
+	// Columns by their db tag, in order of the fields
+	columns   []string
+	columnSet map[string]bool
+	// Index of fields by their column
+	fields map[string][]int
+	// Index of the embedded QueryGenerator field, nil if there isn't any
+	generator []int
+	// Relations by their field name
+	relations map[string]*relation
+	// Column of the `softDelete:"+"` field, rows which it isn't NULL are
+	// deleted, empty if rows get deleted for real
+	softDelete string
+	// Columns of `autoCreateTime:"+"` fields which get the time of insert
+	// if they are zero and `autoUpdateTime:"+"` fields which get the time
+	// of every insert and update
+	autoCreateTime []string
+	autoUpdateTime []string
+	// Column of the `version:"+"` field which updates of the row check and
+	// increment, empty if rows don't get locked optimistically
+	version string
+}
+
+var metadataCache sync.Map
+
+var queryGeneratorType = reflect.TypeOf((*QueryGenerator)(nil)).Elem()
+
+// Returns metadata of a struct type or a pointer to it
+func metadataOf(dataType reflect.Type) *metadata {
+	for dataType.Kind() == reflect.Ptr {
+		dataType = dataType.Elem()
+	}
+	if cached, ok := metadataCache.Load(dataType); ok {
+		return cached.(*metadata)
+	}
+
+	meta := &metadata{
+		table:     tableName(dataType),
+		columnSet: map[string]bool{},
+		fields:    map[string][]int{},
+		relations: map[string]*relation{},
+	}
+	for _, f := range reflect.VisibleFields(dataType) {
+		if f.Anonymous && f.Type == queryGeneratorType && meta.generator == nil {
+			meta.generator = f.Index
+			continue
+		}
+		if name := f.Tag.Get("db"); f.IsExported() && name != "-" && name != "" {
+			meta.columns = append(meta.columns, name)
+			meta.columnSet[name] = true
+			meta.fields[name] = f.Index
+			if f.Tag.Get("softDelete") == "+" {
+				if f.Type != reflect.TypeOf(&time.Time{}) {
+					panic(fmt.Errorf("repositories: soft delete field `%s` is not *time.Time", f.Name))
+				}
+				meta.softDelete = name
+			}
+			if f.Tag.Get("autoCreateTime") == "+" || f.Tag.Get("autoUpdateTime") == "+" {
+				if f.Type != reflect.TypeOf(time.Time{}) && f.Type != reflect.TypeOf(&time.Time{}) {
+					panic(fmt.Errorf("repositories: auto time field `%s` is not time.Time", f.Name))
+				}
+				if f.Tag.Get("autoCreateTime") == "+" {
+					meta.autoCreateTime = append(meta.autoCreateTime, name)
+				} else {
+					meta.autoUpdateTime = append(meta.autoUpdateTime, name)
+				}
+			}
+			if f.Tag.Get("version") == "+" {
+				if !reflect.Zero(f.Type).CanInt() {
+					panic(fmt.Errorf("repositories: version field `%s` is not an integer", f.Name))
+				}
+				meta.version = name
+			}
+		}
+		if rel := parseRelation(f); rel != nil {
+			meta.relations[f.Name] = rel
+		}
+	}
+	cached, _ := metadataCache.LoadOrStore(dataType, meta)
+	return cached.(*metadata)
+}
+
+func tableName(dataType reflect.Type) string {
+	if tabler, ok := reflect.New(dataType).Interface().(Tabler); ok {
+		return tabler.TableName()
+	}
+	name := strings.Builder{}
+	for i, r := range dataType.Name() {
+		if unicode.IsUpper(r) && i > 0 {
+			name.WriteByte('_')
+		}
+		name.WriteRune(unicode.ToLower(r))
+	}
+	return name.String() + "s"
+}
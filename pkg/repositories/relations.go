@@ -0,0 +1,258 @@
+package repositories
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/georgysavva/scany/v2/sqlscan"
+)
+
+// Relations get declared by tags of the fields which hold related rows
+//
+//	// tokens.user_id references users.id, second key is `id` by default
+//	User *User `json:"-" belongsTo:"user_id,id"`
+//
+//	// tokens.user_id references users.id, second key is `id` by default
+//	Tokens []*Token `json:"-" hasMany:"user_id,id"`
+//
+//	// users_groups.user_id references users.id and users_groups.group_id references groups.id
+//	Groups []*Group `json:"-" manyToMany:"users_groups,user_id,group_id"`
+type relationKind int
+
+const (
+	belongsTo relationKind = iota
+	hasMany
+	manyToMany
+)
+
+type relation struct {
+	kind  relationKind
+	field []int
+	// Struct type of related rows
+	related reflect.Type
+	// belongsTo: column of the row which references `key` of related rows
+	// hasMany: column of related rows which references `key` of the row
+	// manyToMany: column of the join table which references id of related rows
+	foreignKey string
+	key        string
+	// manyToMany: join table and its column which references id of the row
+	joinTable string
+	joinKey   string
+}
+
+// Returns relation of a field if it has a relation tag
+func parseRelation(f reflect.StructField) *relation {
+	rel := &relation{field: f.Index, key: "id"}
+	var keys []string
+	if tag, ok := f.Tag.Lookup("belongsTo"); ok {
+		rel.kind, keys = belongsTo, strings.Split(tag, ",")
+	} else if tag, ok := f.Tag.Lookup("hasMany"); ok {
+		rel.kind, keys = hasMany, strings.Split(tag, ",")
+	} else if tag, ok := f.Tag.Lookup("manyToMany"); ok {
+		rel.kind, keys = manyToMany, strings.Split(tag, ",")
+	} else {
+		return nil
+	}
+
+	fieldType := f.Type
+	if rel.kind != belongsTo {
+		if fieldType.Kind() != reflect.Slice {
+			panic(fmt.Errorf("repositories: relation field `%s` is not a slice", f.Name))
+		}
+		fieldType = fieldType.Elem()
+	}
+	if fieldType.Kind() != reflect.Ptr || fieldType.Elem().Kind() != reflect.Struct {
+		panic(fmt.Errorf("repositories: related rows of `%s` are not pointers to struct", f.Name))
+	}
+	rel.related = fieldType.Elem()
+
+	switch {
+	case rel.kind == manyToMany && len(keys) == 3:
+		rel.joinTable, rel.joinKey, rel.foreignKey = keys[0], keys[1], keys[2]
+	case rel.kind != manyToMany && (len(keys) == 1 || len(keys) == 2) && keys[0] != "":
+		rel.foreignKey = keys[0]
+		if len(keys) == 2 {
+			rel.key = keys[1]
+		}
+	default:
+		panic(fmt.Errorf("repositories: relation tag of `%s` is invalid", f.Name))
+	}
+	return rel
+}
+
+// Sets the embedded QueryGenerator of a loaded row if it isn't set and
+// snapshots the row, so its updates only write changed columns, rows
+// without a generator have nowhere to keep the snapshot
+func bindGenerator(row reflect.Value, meta *metadata, dbType string) {
+	if meta.generator == nil {
+		return
+	}
+	field := row.Elem().FieldByIndex(meta.generator)
+	if field.IsNil() {
+		generator := NewQueryGenerator(meta.table)
+		generator.SetRowData(row.Interface())
+		generator.SetDbType(dbType)
+		field.Set(reflect.ValueOf(generator))
+	}
+	(&Query{row: row.Interface(), dialect: GetDialect(dbType)}).Snapshot()
+}
+
+// Loads relation `name` of `rows` (a slice of pointers to struct) with one
+// query, related rows get matched to rows by their keys
+func preload(ctx context.Context, db DB, dbType string, rows reflect.Value, name string) error {
+	meta := metadataOf(rows.Type().Elem())
+	rel, ok := meta.relations[name]
+	if !ok {
+		return fmt.Errorf("repositories: `%s` is not a relation of `%s`", name, meta.table)
+	}
+	related := metadataOf(rel.related)
+
+	// keys of rows which related rows reference or get referenced by
+	ownKey := rel.key
+	switch rel.kind {
+	case belongsTo:
+		ownKey = rel.foreignKey
+	case manyToMany:
+		ownKey = "id"
+	}
+	if !meta.columnSet[ownKey] {
+		return fmt.Errorf("repositories: `%s` is not a column of `%s`", ownKey, meta.table)
+	}
+	keys := []any{}
+	seen := map[string]bool{}
+	for i := 0; i < rows.Len(); i++ {
+		value := reflect.Indirect(rows.Index(i).Elem().FieldByIndex(meta.fields[ownKey]))
+		if !value.IsValid() || seen[fmt.Sprint(value.Interface())] {
+			continue
+		}
+		seen[fmt.Sprint(value.Interface())] = true
+		keys = append(keys, value.Interface())
+	}
+
+	// related rows by the key which matches them to rows
+	loaded := map[string][]reflect.Value{}
+	if len(keys) > 0 {
+		var err error
+		if rel.kind == manyToMany {
+			err = loadThroughJoin(ctx, db, dbType, rel, related, keys, loaded)
+		} else {
+			err = loadRelated(ctx, db, dbType, rel, related, keys, loaded)
+		}
+		if err != nil {
+			return err
+		}
+	}
+
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i).Elem()
+		field := row.FieldByIndex(rel.field)
+		value := reflect.Indirect(row.FieldByIndex(meta.fields[ownKey]))
+		matches := []reflect.Value{}
+		if value.IsValid() {
+			matches = loaded[fmt.Sprint(value.Interface())]
+		}
+		if rel.kind == belongsTo {
+			if len(matches) > 0 {
+				field.Set(matches[0])
+			} else {
+				field.Set(reflect.Zero(field.Type()))
+			}
+			continue
+		}
+		slice := reflect.MakeSlice(field.Type(), 0, len(matches))
+		field.Set(reflect.Append(slice, matches...))
+	}
+	return nil
+}
+
+// Loads related rows of belongsTo and hasMany relations
+func loadRelated(ctx context.Context, db DB, dbType string, rel *relation, related *metadata, keys []any, loaded map[string][]reflect.Value) error {
+	// column of related rows which matches them to rows
+	matchBy := rel.key
+	if rel.kind == hasMany {
+		matchBy = rel.foreignKey
+	}
+	q := &Query{tableName: related.table, row: reflect.New(rel.related).Interface(), dbType: dbType, dialect: GetDialect(dbType)}
+	wheres, err := q.exprWheres(In(matchBy, keys))
+	if err != nil {
+		return err
+	}
+	q.SelectWhere(wheres)
+
+	results := reflect.New(reflect.SliceOf(reflect.PointerTo(rel.related)))
+	query, args := q.queryArgs()
+	if err := sqlscan.Select(ctx, db, results.Interface(), query, args...); err != nil {
+		return queryError(err, query)
+	}
+	if err := afterLoad(ctx, db, results.Interface()); err != nil {
+		return err
+	}
+	results = results.Elem()
+	for i := 0; i < results.Len(); i++ {
+		result := results.Index(i)
+		bindGenerator(result, related, dbType)
+		key := fmt.Sprint(reflect.Indirect(result.Elem().FieldByIndex(related.fields[matchBy])).Interface())
+		loaded[key] = append(loaded[key], result)
+	}
+	return nil
+}
+
+// Loads related rows of manyToMany relations joined by the join table
+func loadThroughJoin(ctx context.Context, db DB, dbType string, rel *relation, related *metadata, keys []any, loaded map[string][]reflect.Value) error {
+	dialect := GetDialect(dbType)
+	columns := make([]string, len(related.columns))
+	for i, column := range related.columns {
+		columns[i] = "related." + column
+	}
+	r := newRenderer(dialect, related.table, reflect.New(rel.related).Interface(), nil)
+	placeholders := make([]string, len(keys))
+	for i, key := range keys {
+		placeholders[i] = r.bind(key)
+	}
+	query := fmt.Sprintf(
+		"SELECT %s, middle.%s FROM %s related JOIN %s middle ON related.id = middle.%s WHERE middle.%s IN (%s)",
+		strings.Join(columns, ", "), rel.joinKey, related.table, rel.joinTable, rel.foreignKey, rel.joinKey, strings.Join(placeholders, ", "),
+	)
+	// deleted rows of soft deleted models don't get loaded
+	if related.softDelete != "" {
+		query += fmt.Sprintf(" AND related.%s IS NULL", related.softDelete)
+	}
+
+	results, err := db.QueryContext(ctx, query, r.args...)
+	if err != nil {
+		return queryError(err, query)
+	}
+	defer results.Close()
+	rows, rowKeys := []reflect.Value{}, []string{}
+	for results.Next() {
+		result := reflect.New(rel.related)
+		var key any
+		dests := make([]any, 0, len(related.columns)+1)
+		for _, column := range related.columns {
+			dests = append(dests, result.Elem().FieldByIndex(related.fields[column]).Addr().Interface())
+		}
+		if err := results.Scan(append(dests, &key)...); err != nil {
+			return queryError(err, query)
+		}
+		if bytes, ok := key.([]byte); ok {
+			key = string(bytes)
+		}
+		rows, rowKeys = append(rows, result), append(rowKeys, fmt.Sprint(key))
+	}
+	if err := results.Err(); err != nil {
+		return queryError(err, query)
+	}
+	results.Close()
+
+	// hooks can query, so they get called after results got closed
+	for i, result := range rows {
+		if err := afterLoad(ctx, db, result.Interface()); err != nil {
+			return err
+		}
+		bindGenerator(result, related, dbType)
+		loaded[rowKeys[i]] = append(loaded[rowKeys[i]], result)
+	}
+	return nil
+}
@@ -7,6 +7,7 @@ import (
 	"fmt"
 	"reflect"
 	"service/pkg/errors"
+	"sort"
 	"strings"
 	"time"
 
@@ -19,13 +20,65 @@ type Query struct {
 	row       any
 	query     string
 	dbType    string
+	dialect   Dialect
+	// Columns which current insert query returns
+	returning []string
+	// Rows which current insert query inserts
+	inserted []any
+	// Statements of current insert query if its rows got chunked
+	batches []batch
+	// Bound parameters of the current query
+	args []any
+	// Which rows of soft deleted models the current query sees
+	scope scope
+	// Reports if current update query checks version of the row
+	versioned bool
+	// Reports if current update query changes nothing, so it doesn't get executed
+	noop bool
+	// Reports if current query updates current row, so it gets snapshot after it
+	updatesMe bool
+	// Reports if current query updates or deletes many rows, so executors
+	// return count of them
+	bulk bool
+	// Operation of current query on its rows which hooks get called for and
+	// the generation of it which gets repeated if Before hooks changed rows
+	operation  operation
+	regenerate func()
+	// Time of current operation which auto time fields get, it stays the
+	// same when the query gets regenerated
+	now time.Time
+	// Columns which current update query writes, nil => changed columns
+	only []string
+	// Formatted values of columns of the row when it got loaded or saved,
+	// nil if the row isn't loaded, then updates write all columns
+	snapshot map[string]string
 }
 
+// A statement of a chunked insert and rows which it inserts
+type batch struct {
+	query    string
+	inserted []any
+}
+
+// Which rows of soft deleted models queries see
+type scope int
+
+const (
+	// Rows which are not deleted
+	scopeDefault scope = iota
+	scopeWithDeleted
+	scopeOnlyDeleted
+)
+
 type QueryGenerator interface {
 	// Sets the current row
 	SetRowData(row any)
+	// Returns the current row
+	GetRowData() any
 	// Sets the table name of the current row
 	SetTableName(tableName string)
+	// Returns the table name of the current row
+	GetTableName() string
 	// Sets current database type to generate right query
 	SetDbType(dbType string)
 	// Returns current db type
@@ -33,39 +86,94 @@ type QueryGenerator interface {
 
 	// Returns select fields for select operation
 	GetSelectFields(prefix ...string) string
-	// Returns insert fields for insert into operation
+	// Returns insert fields for insert into operation, auto time fields of
+	// the row get set first
 	GetInsertFields() (string, string)
-	// Returns update fields for update operations
+	// Returns update fields for update operations, only columns which
+	// changed since the row got loaded if it did, empty if nothing
+	// changed, otherwise autoUpdateTime fields of the row get set first
 	GetUpdateFields() string
+	// Records current values of the row as its loaded values, executors
+	// do it after the row gets loaded, inserted or updated
+	Snapshot() QueryGenerator
+	// Returns columns which changed since the row got loaded, nil if it isn't loaded
+	DirtyFields() []string
 	// Formats all passed wheres in a string with `and` operator between them and `=` operator for key values
 	GetWheres(where map[string]any) string
 	// Formats all passed wheres in a string with `or` operator between them and `Like` operator for key values
 	GetLikeWheres(where map[string]string) string
+	// Formats an expression, its values get bound as parameters of the
+	// query which gets generated next and its columns have to be db tags of the
+	// row, nil renders nothing so it matches all rows
+	GetExprWheres(where Expr) string
 
 	// Generates a insert statement based on the row into the query builder
 	InsertInto() QueryGenerator
-	// Generates a insert statement of a slice
+	// Generates insert statements of a slice, rows get chunked into as many
+	// statements as row and length limits of the database need, which get
+	// executed one by one in a transaction, see Dialect.BatchSize
 	InsertIntoMulti(data []QueryGenerator) QueryGenerator
-	// Generates an update statement which updates current row
+	// Generates an insert statement of current row which updates
+	// `updateColumns` of the existing row instead if the row conflicts on
+	// `conflictColumns`, which have to be a unique key. No updateColumns =>
+	// the existing row gets loaded into current row instead, insert hooks get called
+	//
+	// mysql ignores `conflictColumns` and updates the row which conflicts on
+	// any unique key, so on mysql they should be the only unique key of the
+	// table besides the primary key
+	Upsert(conflictColumns []string, updateColumns []string) QueryGenerator
+	// Generates an update statement which updates current row, rows of
+	// models with a `version:"+"` field only get updated if their version
+	// didn't change since they got read, otherwise executors return ErrStaleRow
+	//
+	// Only columns which changed since the row got loaded get written, if
+	// nothing changed the query doesn't get executed
 	UpdateMe() QueryGenerator
+	// Generates an update statement like UpdateMe which only writes `columns`
+	// of current row, whether they changed or not
+	UpdateFields(columns ...string) QueryGenerator
+	// Select queries which get generated next see deleted rows of soft
+	// deleted models too, they only see rows which are not deleted by default
+	WithDeleted() QueryGenerator
+	// Select queries which get generated next only see deleted rows of
+	// soft deleted models
+	OnlyDeleted() QueryGenerator
+	// Generates an update statement which undeletes current row of a soft deleted model
+	Restore() QueryGenerator
+	// Generates a delete statement which deletes current row for real, even
+	// if the model is soft deleted
+	ForceDelete() QueryGenerator
 	// Generates a select statement and generates where with `GetWheres` function
 	Select(optionalWhere ...map[string]any) QueryGenerator
 	// Generates a select statement
 	SelectWhere(where string) QueryGenerator
+	// Generates a select statement and generates where with `GetExprWheres` function
+	SelectExpr(where Expr) QueryGenerator
 	// Adds order into the select query
 	OrderBy(orderBy string, ascOrDesc string) QueryGenerator
 	// Adds pagination into the select query
 	Paginate(limit, whichPage int) QueryGenerator
-	// Deletes current data from database
+	// Deletes current data from database, rows of soft deleted models get
+	// marked as deleted by setting their `softDelete:"+"` field
 	DeleteMe() QueryGenerator
 	// Generates an sql statement which will delete specific data with desired specifications
 	Delete(optionalWhere ...map[string]any) QueryGenerator
 	// Generates an sql statement which counts all the data with the same specifications
 	SelectCount(optionalWhere ...map[string]any) QueryGenerator
+	// Generates an sql statement which counts all the data matching the expression
+	SelectCountExpr(where Expr) QueryGenerator
 	// Generates an update statement based on changed information in the row
 	Update(optionalWhere ...map[string]any) QueryGenerator
 	// Generates an update statement with desired specifications
 	UpdateSpecific(set map[string]any, optionalWhere ...map[string]any) QueryGenerator
+	// Generates an update which sets columns of rows matching `where` (nil =>
+	// all rows) to values of `set`, executors return count of updated rows
+	// and hooks don't get called for them
+	UpdateWhere(where Expr, set map[string]any) QueryGenerator
+	// Generates a delete of rows which match `where` (nil => all rows), rows
+	// of soft deleted models get marked as deleted, executors return count
+	// of deleted rows and hooks don't get called for them
+	DeleteWhere(where Expr) QueryGenerator
 	// Generates a sql query which will get all information of the current row based on id of the row
 	GetMe() QueryGenerator
 	// An alias for GetMe
@@ -142,37 +250,43 @@ type QueryGenerator interface {
 	// # Returns 0 if couldn't give that id
 	//
 	// Query which is recorded inside will get removed after execution of this method.
-	ExecQuery(ctx context.Context, db *sql.DB) int64
+	ExecQuery(ctx context.Context, db DB) int64
+	// ExecQueryErr is like ExecQuery but returns errors, see Classify
+	// for errors of the database which it can return, UpdateWhere and
+	// DeleteWhere return count of affected rows instead of an id
+	ExecQueryErr(ctx context.Context, db DB) (int64, error)
 	// ExecQueryRow executes a query that is expected to return one row.
 	//
 	// # Used for SelectOneRow Operations
 	//
 	// Query which is recorded inside will get removed after execution of this method.
-	ExecQueryRow(ctx context.Context, db *sql.DB)
+	ExecQueryRow(ctx context.Context, db DB)
 	// ExecQueryRowErr executes a query that is expected to return one row.
 	//
 	// # Used for SelectOneRow Operations
 	//
 	// Query which is recorded inside will get removed after execution of this method.
-	ExecQueryRowErr(ctx context.Context, db *sql.DB) error
+	ExecQueryRowErr(ctx context.Context, db DB) error
 	// ExecQueryCount executes a query that is expected to return one row.
 	//
 	// # Used for SelectOneRow Operations
 	//
 	// Query which is recorded inside will get removed after execution of this method.
-	ExecQueryCount(ctx context.Context, db *sql.DB) int64
+	ExecQueryCount(ctx context.Context, db DB) int64
+	// ExecQueryCountErr is like ExecQueryCount but returns errors
+	ExecQueryCountErr(ctx context.Context, db DB) (int64, error)
 	// ExecQueryMulti executes a query that is expected to return multiple.
 	//
 	// # Used for SelectMultipleRows Operations
 	//
 	// Query which is recorded inside will get removed after execution of this method.
-	ExecQueryMulti(ctx context.Context, db *sql.DB, scanInto any)
+	ExecQueryMulti(ctx context.Context, db DB, scanInto any)
 	// ExecQueryMultiErr executes a query that is expected to return multiple.
 	//
 	// # Used for SelectMultipleRows Operations
 	//
 	// Query which is recorded inside will get removed after execution of this method.
-	ExecQueryMultiErr(ctx context.Context, db *sql.DB, scanInto any) error
+	ExecQueryMultiErr(ctx context.Context, db DB, scanInto any) error
 }
 
 // Checks if passed input is a struct
@@ -216,78 +330,337 @@ func (q *Query) formatValue(input any, nilIfEmpty ...bool) string {
 		}
 		return fmt.Sprint(input)
 	case time.Time:
-		return fmt.Sprintf("'%s'", input.UTC().Format(time.RFC3339Nano))
+		return q.dialect.Time(input)
 	case bool:
-		if input {
-			return "TRUE"
-		} else {
-			return "FALSE"
-		}
+		return q.dialect.Bool(input)
 	case string:
 		if input == "" && nilOnEmpty {
 			return "NULL"
 		}
-		if q.dbType == "postgres" || q.dbType == "sqlite3" {
-			return fmt.Sprintf("'%s'", safePostgresSqlite(input))
-		}
-		return fmt.Sprintf("'%s'", input)
+		return q.dialect.String(input)
 	case nil:
 		return "NULL"
 	default:
-		return fmt.Sprintf("'%s'", input)
+		return q.dialect.String(fmt.Sprint(input))
 	}
 }
 
-func safePostgresSqlite(data string) string {
-	var builder strings.Builder
-	for _, char := range data {
-		if char == '\'' {
-			builder.WriteString("''")
+// Returns all columns of the row
+func (q *Query) columns() []string {
+	dataType, _ := q.structCheck(q.row)
+	return metadataOf(dataType).columns
+}
+
+// Returns value of Id field of a row, 0 if it has no id
+func rowId(row any) int64 {
+	value := reflect.Indirect(reflect.ValueOf(row))
+	if value.Kind() != reflect.Struct {
+		return 0
+	}
+	if id := value.FieldByName("Id"); id.IsValid() && id.CanInt() {
+		return id.Int()
+	}
+	return 0
+}
+
+// Returns the soft delete column of the row, empty if the model isn't soft deleted
+func (q *Query) softDeleteColumn() string {
+	dataType, _ := q.structCheck(q.row)
+	return metadataOf(dataType).softDelete
+}
+
+// Adds condition of the scope into wheres of a select, `prefix` is the
+// alias of the table in the query
+func (q *Query) scoped(wheres string, prefix string) string {
+	column := q.softDeleteColumn()
+	if column == "" || q.scope == scopeWithDeleted {
+		return wheres
+	}
+	if prefix != "" {
+		column = prefix + "." + column
+	}
+	condition := column + " IS NULL"
+	if q.scope == scopeOnlyDeleted {
+		condition = column + " IS NOT NULL"
+	}
+	if wheres == "" {
+		return condition
+	}
+	return fmt.Sprintf("(%s) AND %s", wheres, condition)
+}
+
+// Sets the soft delete field of the row
+func (q *Query) setDeletedAt(deletedAt *time.Time) {
+	dataType, dataValue := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	dataValue.FieldByIndex(meta.fields[meta.softDelete]).Set(reflect.ValueOf(deletedAt))
+}
+
+// Sets auto time fields of the row and version of a new row, autoCreateTime
+// fields only get set on insert if they are zero
+func (q *Query) touch(insert bool) {
+	dataType, dataValue := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	now := q.stamp()
+	setTime := func(column string) {
+		field := dataValue.FieldByIndex(meta.fields[column])
+		if field.Kind() == reflect.Ptr {
+			field.Set(reflect.ValueOf(&now))
 		} else {
-			builder.WriteRune(char)
+			field.Set(reflect.ValueOf(now))
+		}
+	}
+	for _, column := range meta.autoUpdateTime {
+		setTime(column)
+	}
+	if !insert {
+		return
+	}
+	for _, column := range meta.autoCreateTime {
+		if dataValue.FieldByIndex(meta.fields[column]).IsZero() {
+			setTime(column)
+		}
+	}
+	if meta.version != "" {
+		if field := dataValue.FieldByIndex(meta.fields[meta.version]); field.IsZero() {
+			field.SetInt(1)
 		}
 	}
-	return builder.String()
+}
+
+// Returns time of current operation
+func (q *Query) stamp() time.Time {
+	if q.now.IsZero() {
+		q.now = time.Now()
+	}
+	return q.now
 }
 
 func (q *Query) InsertInto() QueryGenerator {
 	keys, values := q.GetInsertFields()
-	q.query = fmt.Sprintf("INSERT INTO %s (%s) VALUES(%s)", q.tableName, keys, values)
+	if q.dialect.CanReturn() {
+		q.returning = q.columns()
+	}
+	q.inserted = []any{q.row}
+	q.query = q.dialect.Insert(q.tableName, keys, "("+values+")", q.returning)
+	q.operation, q.regenerate = opInsert, func() { q.InsertInto() }
 	return q
 }
 
 func (q *Query) InsertIntoMulti(data []QueryGenerator) QueryGenerator {
 	q.sliceCheck(data)
 	keys, _ := q.GetInsertFields()
-	values := ""
+	if q.dialect.CanReturn() {
+		q.returning = q.columns()
+	}
+	// rows get split into statements which the database accepts
+	maxRows, maxLength := q.dialect.BatchSize()
+	// returned rows get matched to the inserted ones by their order, and
+	// databases without RETURNING only give id of a single inserted row,
+	// ids of many rows aren't consecutive with interleaved auto increments
+	if !q.dialect.CanReturn() || (len(q.returning) > 0 && !q.dialect.ReturnsInOrder()) {
+		maxRows = 1
+	}
+	length := len(q.dialect.Insert(q.tableName, keys, "", q.returning))
+	q.inserted = make([]any, 0, len(data))
+	q.batches = nil
+	queries := []string{}
+	values, inserted := "", []any{}
+	flush := func() {
+		query := q.dialect.Insert(q.tableName, keys, values, q.returning)
+		q.batches = append(q.batches, batch{query: query, inserted: inserted})
+		q.inserted = append(q.inserted, inserted...)
+		queries = append(queries, query)
+		values, inserted = "", []any{}
+	}
 	for _, generator := range data {
+		// all rows get the time of the insert, models hold their own generator
+		element, ok := generator.(*Query)
+		if !ok {
+			element = (&Query{row: generator.GetRowData()}).rowQuery()
+		}
+		element.now = q.stamp()
 		_, elementValues := generator.GetInsertFields()
+		element.now = time.Time{}
+		row := "(" + elementValues + ")"
+		if values != "" {
+			full := maxRows > 0 && len(inserted) >= maxRows
+			long := maxLength > 0 && length+len(values)+len(", ")+len(row) > maxLength
+			if full || long {
+				flush()
+			}
+		}
 		if values == "" {
-			values = "(" + elementValues + ")"
-			continue
+			values = row
+		} else {
+			values += ", " + row
 		}
-		values += ", (" + elementValues + ")"
+		inserted = append(inserted, generator.GetRowData())
+	}
+	if values != "" {
+		flush()
 	}
-	q.query = fmt.Sprintf("INSERT INTO %s (%s) VALUES %s", q.tableName, keys, values)
+	q.query = strings.Join(queries, ";\n")
+	q.operation, q.regenerate = opInsert, func() { q.InsertIntoMulti(data) }
+	return q
+}
+
+func (q *Query) Upsert(conflictColumns []string, updateColumns []string) QueryGenerator {
+	dataType, _ := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	if len(conflictColumns) == 0 {
+		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", fmt.Sprintf("upsert of %s has no conflict column", q.tableName)))
+	}
+	for _, column := range append(append([]string{}, conflictColumns...), updateColumns...) {
+		if !meta.columnSet[column] {
+			panic(errors.New(errors.UnexpectedStatus, "InternalServerError", fmt.Sprintf("%s is not a column of %s", column, q.tableName)))
+		}
+	}
+
+	keys, values := q.GetInsertFields()
+	sets := []string{}
+	for _, column := range updateColumns {
+		sets = append(sets, column+" = "+q.dialect.Excluded(column))
+	}
+	if len(sets) == 0 {
+		// the existing row doesn't change, it only gets returned
+		for _, column := range conflictColumns {
+			sets = append(sets, column+" = "+q.dialect.Excluded(column))
+		}
+	} else {
+		// auto update times and versions follow the update unless they are set
+		for _, column := range meta.autoUpdateTime {
+			if !contains(updateColumns, column) {
+				sets = append(sets, column+" = "+q.dialect.Excluded(column))
+			}
+		}
+		if meta.version != "" && !contains(updateColumns, meta.version) {
+			sets = append(sets, fmt.Sprintf("%s = %s.%s + 1", meta.version, q.tableName, meta.version))
+		}
+	}
+	if q.dialect.CanReturn() {
+		q.returning = q.columns()
+	}
+	q.inserted = []any{q.row}
+	q.query = q.dialect.Upsert(q.tableName, keys, "("+values+")", conflictColumns, sets, q.returning)
+	q.operation, q.regenerate = opInsert, func() { q.Upsert(conflictColumns, updateColumns) }
 	return q
 }
 
 func (q *Query) UpdateMe() QueryGenerator {
-	value := reflect.ValueOf(q.row)
-	id := value.Elem().FieldByName("Id").Interface()
+	dataType, dataValue := q.structCheck(q.row)
+	where := map[string]any{"id": dataValue.FieldByName("Id").Interface()}
+	if column := metadataOf(dataType).version; column != "" {
+		where[column] = dataValue.FieldByIndex(metadataOf(dataType).fields[column]).Interface()
+		q.versioned = true
+	}
 	sets := q.GetUpdateFields()
-	wheres := q.GetWheres(map[string]any{"id": id})
+	if sets == "" {
+		q.noop = true
+		return q
+	}
+	q.updatesMe = true
+	wheres := q.GetWheres(where)
 	q.query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", q.tableName, sets, wheres)
+	q.operation, q.regenerate = opUpdate, func() { q.UpdateMe() }
+	return q
+}
+
+func (q *Query) UpdateFields(columns ...string) QueryGenerator {
+	dataType, _ := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	for _, column := range columns {
+		if !meta.columnSet[column] {
+			panic(errors.New(errors.UnexpectedStatus, "InternalServerError", fmt.Sprintf("%s is not a column of %s", column, q.tableName)))
+		}
+	}
+	q.only = append([]string{}, columns...)
+	return q.UpdateMe()
+}
+
+// Returns the generator which holds state of the row, the embedded one if
+// the row has it, otherwise the query itself
+func (q *Query) rowQuery() *Query {
+	if q.row == nil {
+		return q
+	}
+	dataType, dataValue := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	if meta.generator == nil {
+		return q
+	}
+	if generator, ok := dataValue.FieldByIndex(meta.generator).Interface().(*Query); ok && generator != nil {
+		return generator
+	}
+	return q
+}
+
+// Returns formatted values of columns of the row
+func (q *Query) values() map[string]string {
+	dataType, dataValue := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	values := make(map[string]string, len(meta.columns))
+	for _, column := range meta.columns {
+		fieldValue := dataValue.FieldByIndex(meta.fields[column])
+		for fieldValue.Kind() == reflect.Ptr {
+			fieldValue = fieldValue.Elem()
+		}
+		var value any = nil
+		if fieldValue.IsValid() {
+			value = fieldValue.Interface()
+		}
+		values[column] = q.formatValue(value)
+	}
+	return values
+}
+
+func (q *Query) Snapshot() QueryGenerator {
+	q.rowQuery().snapshot = q.values()
 	return q
 }
 
+// Snapshots `row` which q inserted or loaded
+func (q *Query) snapshotOf(row any) {
+	(&Query{row: row, dialect: q.dialect}).Snapshot()
+}
+
+func (q *Query) DirtyFields() []string {
+	snapshot := q.rowQuery().snapshot
+	if snapshot == nil {
+		return nil
+	}
+	dataType, _ := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	values := q.values()
+	dirty := []string{}
+	for _, column := range meta.columns {
+		// columns which the generator writes itself don't make a row dirty
+		if column == meta.version || column == meta.softDelete || contains(meta.autoUpdateTime, column) {
+			continue
+		}
+		if value, ok := snapshot[column]; !ok || value != values[column] {
+			dirty = append(dirty, column)
+		}
+	}
+	return dirty
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
+
 func (q *Query) Select(optionalWhere ...map[string]any) QueryGenerator {
 	where := map[string]any{}
 	if len(optionalWhere) != 0 {
 		where = optionalWhere[0]
 	}
 	keys := q.GetSelectFields()
-	wheres := q.GetWheres(where)
+	wheres := q.scoped(q.GetWheres(where), "")
 	if wheres != "" {
 		q.query = fmt.Sprintf("SELECT %s FROM %s WHERE %s", keys, q.tableName, wheres)
 	} else {
@@ -298,6 +671,7 @@ func (q *Query) Select(optionalWhere ...map[string]any) QueryGenerator {
 
 func (q *Query) SelectWhere(wheres string) QueryGenerator {
 	keys := q.GetSelectFields()
+	wheres = q.scoped(wheres, "")
 	if wheres != "" {
 		q.query = fmt.Sprintf("SELECT %s FROM %s WHERE %s", keys, q.tableName, wheres)
 	} else {
@@ -306,6 +680,10 @@ func (q *Query) SelectWhere(wheres string) QueryGenerator {
 	return q
 }
 
+func (q *Query) SelectExpr(where Expr) QueryGenerator {
+	return q.SelectWhere(q.GetExprWheres(where))
+}
+
 func (q *Query) OrderBy(orderBy string, ascOrDesc string) QueryGenerator {
 	if q.query != "" {
 		if ascOrDesc == "desc" {
@@ -324,7 +702,7 @@ func (q *Query) OrderBy(orderBy string, ascOrDesc string) QueryGenerator {
 
 func (q *Query) Paginate(limit, whichPage int) QueryGenerator {
 	if q.query != "" {
-		q.query += fmt.Sprintf(" LIMIT %d OFFSET %d", limit, (whichPage-1)*limit)
+		q.query = q.dialect.Paginate(q.query, limit, (whichPage-1)*limit)
 	} else {
 		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", "no query to paginate"))
 	}
@@ -333,9 +711,47 @@ func (q *Query) Paginate(limit, whichPage int) QueryGenerator {
 }
 
 func (q *Query) DeleteMe() QueryGenerator {
+	column := q.softDeleteColumn()
+	if column == "" {
+		return q.ForceDelete()
+	}
+	value := reflect.ValueOf(q.row)
+	id := value.Elem().FieldByName("Id").Interface()
+	now := q.stamp()
+	q.setDeletedAt(&now)
+	q.query = fmt.Sprintf("UPDATE %s SET %s = %s WHERE id = %v", q.tableName, column, q.formatValue(now), id)
+	q.operation = opDelete
+	return q
+}
+
+func (q *Query) ForceDelete() QueryGenerator {
 	value := reflect.ValueOf(q.row)
 	id := value.Elem().FieldByName("Id").Interface()
 	q.query = fmt.Sprintf("DELETE FROM %s WHERE id = %v", q.tableName, id)
+	q.operation = opDelete
+	return q
+}
+
+func (q *Query) Restore() QueryGenerator {
+	column := q.softDeleteColumn()
+	if column == "" {
+		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", fmt.Sprintf("rows of %s are not soft deleted", q.tableName)))
+	}
+	value := reflect.ValueOf(q.row)
+	id := value.Elem().FieldByName("Id").Interface()
+	q.setDeletedAt(nil)
+	q.query = fmt.Sprintf("UPDATE %s SET %s = NULL WHERE id = %v", q.tableName, column, id)
+	q.operation = opUpdate
+	return q
+}
+
+func (q *Query) WithDeleted() QueryGenerator {
+	q.scope = scopeWithDeleted
+	return q
+}
+
+func (q *Query) OnlyDeleted() QueryGenerator {
+	q.scope = scopeOnlyDeleted
 	return q
 }
 
@@ -345,6 +761,12 @@ func (q *Query) Delete(optionalWhere ...map[string]any) QueryGenerator {
 		where = optionalWhere[0]
 	}
 	wheres := q.GetWheres(where)
+	// rows of soft deleted models get marked, already deleted ones keep their time
+	if column := q.softDeleteColumn(); column != "" {
+		q.scope = scopeDefault
+		q.query = fmt.Sprintf("UPDATE %s SET %s = %s WHERE %s", q.tableName, column, q.formatValue(q.stamp()), q.scoped(wheres, ""))
+		return q
+	}
 	if wheres != "" {
 		q.query = fmt.Sprintf("DELETE FROM %s WHERE %s", q.tableName, wheres)
 	} else {
@@ -353,12 +775,109 @@ func (q *Query) Delete(optionalWhere ...map[string]any) QueryGenerator {
 	return q
 }
 
+func (q *Query) UpdateWhere(where Expr, set map[string]any) QueryGenerator {
+	if err := q.updateWhere(where, set); err != nil {
+		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error()))
+	}
+	return q
+}
+
+// Generates UpdateWhere, invalid columns return as errors
+func (q *Query) updateWhere(where Expr, set map[string]any) error {
+	if len(set) == 0 {
+		return fmt.Errorf("repositories: update of `%s` sets no column", q.tableName)
+	}
+	columns := make([]string, 0, len(set))
+	for column := range set {
+		columns = append(columns, column)
+	}
+	sort.Strings(columns)
+
+	dataType, _ := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	rd := newRenderer(q.dialect, q.tableName, q.row, q.args)
+	sets := make([]string, 0, len(columns))
+	for _, column := range columns {
+		sets = append(sets, rd.column(column)+" = "+rd.bind(set[column]))
+	}
+	if rd.err != nil {
+		return rd.err
+	}
+	// auto update times and versions follow the update unless they are set
+	for _, column := range meta.autoUpdateTime {
+		if _, ok := set[column]; !ok {
+			sets = append(sets, column+" = "+rd.bind(q.stamp()))
+		}
+	}
+	if _, ok := set[meta.version]; meta.version != "" && !ok {
+		sets = append(sets, fmt.Sprintf("%s = %s + 1", meta.version, meta.version))
+	}
+
+	q.args = rd.args
+	wheres, err := q.exprWheres(where)
+	if err != nil {
+		return err
+	}
+	q.query = fmt.Sprintf("UPDATE %s SET %s", q.tableName, strings.Join(sets, ", "))
+	if wheres = q.scoped(wheres, ""); wheres != "" {
+		q.query += " WHERE " + wheres
+	}
+	q.bulk = true
+	return nil
+}
+
+func (q *Query) DeleteWhere(where Expr) QueryGenerator {
+	if err := q.deleteWhere(where); err != nil {
+		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error()))
+	}
+	return q
+}
+
+// Generates DeleteWhere, invalid columns return as errors
+func (q *Query) deleteWhere(where Expr) error {
+	column := q.softDeleteColumn()
+	if column == "" {
+		wheres, err := q.exprWheres(where)
+		if err != nil {
+			return err
+		}
+		q.query = "DELETE FROM " + q.tableName
+		if wheres != "" {
+			q.query += " WHERE " + wheres
+		}
+		q.bulk = true
+		return nil
+	}
+
+	// already deleted rows keep their time
+	rd := newRenderer(q.dialect, q.tableName, q.row, q.args)
+	set := fmt.Sprintf("%s = %s", column, rd.bind(q.stamp()))
+	q.args, q.scope = rd.args, scopeDefault
+	wheres, err := q.exprWheres(where)
+	if err != nil {
+		return err
+	}
+	q.query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", q.tableName, set, q.scoped(wheres, ""))
+	q.bulk = true
+	return nil
+}
+
 func (q *Query) SelectCount(optionalWhere ...map[string]any) QueryGenerator {
 	where := map[string]any{}
 	if len(optionalWhere) != 0 {
 		where = optionalWhere[0]
 	}
-	wheres := q.GetWheres(where)
+	wheres := q.scoped(q.GetWheres(where), "")
+	if wheres != "" {
+		q.query = fmt.Sprintf("SELECT COUNT(*) as count FROM %s WHERE %s", q.tableName, wheres)
+	} else {
+		q.query = fmt.Sprintf("SELECT COUNT(*) as count FROM %s", q.tableName)
+	}
+	return q
+}
+
+func (q *Query) SelectCountExpr(where Expr) QueryGenerator {
+	wheres := q.scoped(q.GetExprWheres(where), "")
 	if wheres != "" {
 		q.query = fmt.Sprintf("SELECT COUNT(*) as count FROM %s WHERE %s", q.tableName, wheres)
 	} else {
@@ -373,6 +892,10 @@ func (q *Query) Update(optionalWhere ...map[string]any) QueryGenerator {
 		where = optionalWhere[0]
 	}
 	sets := q.GetUpdateFields()
+	if sets == "" {
+		q.noop = true
+		return q
+	}
 	wheres := q.GetWheres(where)
 	if wheres != "" {
 		q.query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", q.tableName, sets, wheres)
@@ -395,6 +918,17 @@ func (q *Query) UpdateSpecific(set map[string]any, optionalWhere ...map[string]a
 		}
 		sets += fmt.Sprintf(", %s = %s", key, q.formatValue(value))
 	}
+	// auto update times and versions follow the update unless they are set
+	dataType, _ := q.structCheck(q.row)
+	meta := metadataOf(dataType)
+	for _, column := range meta.autoUpdateTime {
+		if _, ok := set[column]; !ok {
+			sets += fmt.Sprintf(", %s = %s", column, q.formatValue(q.stamp()))
+		}
+	}
+	if _, ok := set[meta.version]; meta.version != "" && !ok {
+		sets += fmt.Sprintf(", %s = %s + 1", meta.version, meta.version)
+	}
 	wheres := q.GetWheres(where)
 	if wheres != "" {
 		q.query = fmt.Sprintf("UPDATE %s SET %s WHERE %s", q.tableName, sets, wheres)
@@ -407,7 +941,7 @@ func (q *Query) UpdateSpecific(set map[string]any, optionalWhere ...map[string]a
 func (q *Query) GetMe() QueryGenerator {
 	value := reflect.ValueOf(q.row)
 	id := value.Elem().FieldByName("Id").Interface()
-	q.query = fmt.Sprintf("SELECT * FROM %s WHERE id = %v", q.tableName, id)
+	q.query = fmt.Sprintf("SELECT * FROM %s WHERE %s", q.tableName, q.scoped(fmt.Sprintf("id = %v", id), ""))
 	return q
 }
 
@@ -436,7 +970,7 @@ func (q *Query) OneToMany(destinationTable string, destinationId int64) QueryGen
 			keys += ", main." + name
 		}
 	}
-	q.query = fmt.Sprintf("SELECT DISTINCT %s FROM %s main JOIN %s destination ON main.%s_id = destination.id WHERE destination.id = %d", keys, q.tableName, destinationTable, destinationTable[:len(destinationTable)-1], destinationId)
+	q.query = fmt.Sprintf("SELECT DISTINCT %s FROM %s main JOIN %s destination ON main.%s_id = destination.id WHERE %s", keys, q.tableName, destinationTable, destinationTable[:len(destinationTable)-1], q.scoped(fmt.Sprintf("destination.id = %d", destinationId), "main"))
 	return q
 }
 
@@ -456,7 +990,7 @@ func (q *Query) ManyToMany(middleTable, destinationTable string, destinationId i
 			keys += ", main." + name
 		}
 	}
-	q.query = fmt.Sprintf("SELECT DISTINCT %s FROM %s main JOIN %s middle ON main.id = middle.%s_id JOIN %s destination ON destination.id = middle.%s_id WHERE destination.id = %d", keys, q.tableName, middleTable, q.tableName[:len(q.tableName)-1], destinationTable, destinationTable[:len(destinationTable)-1], destinationId)
+	q.query = fmt.Sprintf("SELECT DISTINCT %s FROM %s main JOIN %s middle ON main.id = middle.%s_id JOIN %s destination ON destination.id = middle.%s_id WHERE %s", keys, q.tableName, middleTable, q.tableName[:len(q.tableName)-1], destinationTable, destinationTable[:len(destinationTable)-1], q.scoped(fmt.Sprintf("destination.id = %d", destinationId), "main"))
 	return q
 }
 
@@ -508,6 +1042,18 @@ func (q *Query) Query() string {
 		output = q.query
 	}
 	q.query = ""
+	q.returning = nil
+	q.inserted = nil
+	q.batches = nil
+	q.args = nil
+	q.scope = scopeDefault
+	q.versioned = false
+	q.noop = false
+	q.updatesMe = false
+	q.bulk = false
+	q.only = nil
+	q.operation, q.regenerate = opNone, nil
+	q.now = time.Time{}
 	return output
 }
 
@@ -515,12 +1061,21 @@ func (q *Query) SetRowData(row any) {
 	q.row = row
 }
 
+func (q *Query) GetRowData() any {
+	return q.row
+}
+
 func (q *Query) SetTableName(tableName string) {
 	q.tableName = tableName
 }
 
+func (q *Query) GetTableName() string {
+	return q.tableName
+}
+
 func (q *Query) SetDbType(dbType string) {
 	q.dbType = dbType
+	q.dialect = GetDialect(dbType)
 }
 
 func (q *Query) GetDbType() string {
@@ -559,6 +1114,7 @@ func (q *Query) GetSelectFields(prefix ...string) string {
 }
 
 func (q *Query) GetInsertFields() (string, string) {
+	q.touch(true)
 	dataType, dataValue := q.structCheck(q.row)
 	keys := ""
 	values := ""
@@ -591,23 +1147,49 @@ func (q *Query) GetInsertFields() (string, string) {
 }
 
 func (q *Query) GetUpdateFields() string {
+	// explicit columns or changed ones, nil => all columns
+	columns := q.only
+	if columns == nil {
+		columns = q.DirtyFields()
+	}
+	if columns != nil && len(columns) == 0 {
+		return ""
+	}
+	q.touch(false)
 	dataType, dataValue := q.structCheck(q.row)
+	meta := metadataOf(dataType)
 	sets := ""
 	for _, f := range reflect.VisibleFields(dataType) {
 		if f.IsExported() {
 			name := f.Tag.Get("db")
 			fieldName := f.Name
-			if name == "-" || name == "" || f.Tag.Get("skipUpdate") == "+" {
+			// creation times don't change and soft delete columns only
+			// change by DeleteMe and Restore
+			if name == "-" || name == "" || f.Tag.Get("skipUpdate") == "+" || f.Tag.Get("autoCreateTime") == "+" || name == meta.softDelete {
+				continue
+			}
+			if columns != nil && !contains(columns, name) && name != meta.version && !contains(meta.autoUpdateTime, name) {
 				continue
 			}
-			value := dataValue.FieldByName(fieldName).Interface()
-			if value != nil {
-				if sets == "" {
-					sets = fmt.Sprintf("%s = %s", name, q.formatValue(value, f.Tag.Get("nilOnEmpty") == "+"))
-					continue
+			set := ""
+			if name == meta.version {
+				set = fmt.Sprintf("%s = %s + 1", name, name)
+			} else {
+				fieldValue := dataValue.FieldByName(fieldName)
+				for fieldValue.Kind() == reflect.Ptr {
+					fieldValue = fieldValue.Elem()
 				}
-				sets += fmt.Sprintf(", %s = %s", name, q.formatValue(value, f.Tag.Get("nilOnEmpty") == "+"))
+				var value any = nil
+				if fieldValue.IsValid() {
+					value = fieldValue.Interface()
+				}
+				set = fmt.Sprintf("%s = %s", name, q.formatValue(value, f.Tag.Get("nilOnEmpty") == "+"))
+			}
+			if sets == "" {
+				sets = set
+				continue
 			}
+			sets += ", " + set
 		}
 	}
 	return sets
@@ -638,80 +1220,269 @@ func (q *Query) GetWheres(where map[string]any) string {
 
 func (q *Query) GetLikeWheres(where map[string]string) string {
 	wheres := ""
-	like := ""
-	if q.dbType == "sqlite3" {
-		like = "LIKE"
-	} else if q.dbType == "postgres" {
-		like = "ILIKE"
-	}
 	for key, value := range where {
 		if wheres == "" {
-			wheres = fmt.Sprintf("%s %s %s", key, like, q.formatValue("%%"+value+"%%"))
+			wheres = q.dialect.Like(key, q.formatValue("%"+value+"%"))
 			continue
 		}
-		wheres += fmt.Sprintf(" OR %s %s %s", key, like, q.formatValue("%%"+value+"%%"))
+		wheres += " OR " + q.dialect.Like(key, q.formatValue("%"+value+"%"))
 	}
 
 	return wheres
 }
 
-func (q *Query) ExecQuery(ctx context.Context, db *sql.DB) int64 {
-	query := q.Query()
-	result, err := db.ExecContext(ctx, query)
+func (q *Query) GetExprWheres(where Expr) string {
+	wheres, err := q.exprWheres(where)
 	if err != nil {
-		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error()+" Query: "+query))
+		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error()))
 	}
+	return wheres
+}
 
-	if rows, err := result.RowsAffected(); err == nil && rows > 0 {
-		if lastId, err := result.LastInsertId(); err == nil && lastId > 0 {
-			reflect.ValueOf(q.row).Elem().FieldByName("Id").Set(reflect.ValueOf(lastId))
-			return lastId
+// Renders `where` and records its bound parameters, invalid columns return
+// as errors and nil renders nothing, so it matches all rows
+func (q *Query) exprWheres(where Expr) (string, error) {
+	if where == nil {
+		return "", nil
+	}
+	r := newRenderer(q.dialect, q.tableName, q.row, q.args)
+	where.render(r)
+	if r.err != nil {
+		return "", r.err
+	}
+	q.args = r.args
+	return r.sql.String(), nil
+}
+
+// Returns the generated query with its bound parameters and resets them
+func (q *Query) queryArgs() (string, []any) {
+	args := q.args
+	return q.Query(), args
+}
+
+func (q *Query) ExecQuery(ctx context.Context, db DB) int64 {
+	id, err := q.ExecQueryErr(ctx, db)
+	if err != nil {
+		panic(ServerError(err))
+	}
+	return id
+}
+
+// Executors return classified errors of the database which contain the query
+func queryError(err error, query string) error {
+	return fmt.Errorf("%w Query: %s", Classify(err), query)
+}
+
+func (q *Query) ExecQueryErr(ctx context.Context, db DB) (int64, error) {
+	if q.noop {
+		q.Query()
+		return rowId(q.row), nil
+	}
+
+	// hooks get called on inserted rows or the row which gets updated or deleted
+	op, rows := q.operation, q.inserted
+	if op != opInsert {
+		rows = []any{q.row}
+	}
+	if op != opNone {
+		called, err := beforeHooks(ctx, db, op, rows)
+		if err != nil {
+			q.Query()
+			return 0, err
+		}
+		if called && q.regenerate != nil {
+			q.regenerate()
 		}
 	}
-	return 0
+	id, err := q.execBatches(ctx, db)
+	if err != nil || op == opNone {
+		return id, err
+	}
+	return id, afterHooks(ctx, db, op, rows)
+}
+
+// Executes statements of a chunked insert one by one, id of the last
+// inserted row returns
+//
+// Statements run in a transaction if `db` is a *sql.DB, so either all
+// rows get inserted or none of them, inside a transaction of the caller
+// rolling back is up to the caller
+func (q *Query) execBatches(ctx context.Context, db DB) (int64, error) {
+	batches, returning := q.batches, q.returning
+	// a single statement is the generated query itself
+	if len(batches) <= 1 {
+		return q.exec(ctx, db)
+	}
+	q.Query()
+
+	var tx *sql.Tx
+	if sqlDB, ok := db.(*sql.DB); ok {
+		var err error
+		if tx, err = sqlDB.BeginTx(ctx, nil); err != nil {
+			return 0, err
+		}
+		defer tx.Rollback()
+		db = tx
+	}
+
+	id := int64(0)
+	for _, batch := range batches {
+		q.query, q.inserted, q.returning = batch.query, batch.inserted, returning
+		var err error
+		if id, err = q.exec(ctx, db); err != nil {
+			return 0, err
+		}
+	}
+	if tx != nil {
+		if err := tx.Commit(); err != nil {
+			return 0, err
+		}
+	}
+	return id, nil
 }
 
-func (q *Query) ExecQueryRow(ctx context.Context, db *sql.DB) {
-	query := q.Query()
-	err := sqlscan.Get(ctx, db, q.row, query)
+// Executes the generated query, see ExecQueryErr
+func (q *Query) exec(ctx context.Context, db DB) (int64, error) {
+	returning, inserted, versioned, updatesMe, bulk := q.returning, q.inserted, q.versioned, q.updatesMe, q.bulk
+	query, args := q.queryArgs()
+
+	// inserted rows get returned by the query itself with all generated columns
+	if len(returning) > 0 {
+		rows, err := db.QueryContext(ctx, query, args...)
+		if err != nil {
+			return 0, queryError(err, query)
+		}
+		defer rows.Close()
+		scanner := sqlscan.NewRowScanner(rows)
+		for i := 0; rows.Next() && i < len(inserted); i++ {
+			if err := scanner.Scan(inserted[i]); err != nil {
+				return 0, queryError(err, query)
+			}
+			q.snapshotOf(inserted[i])
+		}
+		if err := rows.Err(); err != nil {
+			return 0, queryError(err, query)
+		}
+		if len(inserted) == 0 {
+			return 0, nil
+		}
+		return rowId(inserted[len(inserted)-1]), nil
+	}
+
+	result, err := db.ExecContext(ctx, query, args...)
 	if err != nil {
-		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error()+" Query: "+query))
+		return 0, queryError(err, query)
+	}
+	if bulk {
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, queryError(err, query)
+		}
+		return affected, nil
+	}
+
+	// no row matched if another update changed version of the row first
+	if versioned {
+		affected, err := result.RowsAffected()
+		if err != nil {
+			return 0, queryError(err, query)
+		}
+		if affected == 0 {
+			return 0, queryError(ErrStaleRow, query)
+		}
+		dataType, dataValue := q.structCheck(q.row)
+		version := dataValue.FieldByIndex(metadataOf(dataType).fields[metadataOf(dataType).version])
+		version.SetInt(version.Int() + 1)
+	}
+	if updatesMe {
+		q.Snapshot()
+		return rowId(q.row), nil
+	}
+
+	// databases without RETURNING only give the id, so generated columns
+	// of a single inserted row get selected afterwards, InsertIntoMulti
+	// inserts a row per statement on them
+	if len(inserted) == 1 {
+		if lastId, err := result.LastInsertId(); err == nil && lastId > 0 {
+			reflect.ValueOf(inserted[0]).Elem().FieldByName("Id").Set(reflect.ValueOf(lastId))
+			query := fmt.Sprintf("SELECT %s FROM %s WHERE id = %d", q.GetSelectFields(), q.tableName, lastId)
+			if err := sqlscan.Get(ctx, db, inserted[0], query); err != nil {
+				return 0, queryError(err, query)
+			}
+			q.snapshotOf(inserted[0])
+			return lastId, nil
+		}
 	}
+	return 0, nil
 }
 
-func (q *Query) ExecQueryRowErr(ctx context.Context, db *sql.DB) error {
-	query := q.Query()
-	err := sqlscan.Get(ctx, db, q.row, query)
-	return err
+func (q *Query) ExecQueryRow(ctx context.Context, db DB) {
+	if err := q.ExecQueryRowErr(ctx, db); err != nil {
+		panic(ServerError(err))
+	}
 }
 
-func (q *Query) ExecQueryCount(ctx context.Context, db *sql.DB) int64 {
-	query := q.Query()
-	count := int64(-1)
-	err := db.QueryRowContext(ctx, query).Scan(&count)
+func (q *Query) ExecQueryRowErr(ctx context.Context, db DB) error {
+	query, args := q.queryArgs()
+	if err := sqlscan.Get(ctx, db, q.row, query, args...); err != nil {
+		return queryError(err, query)
+	}
+	if err := afterLoad(ctx, db, q.row); err != nil {
+		return err
+	}
+	q.Snapshot()
+	return nil
+}
+
+func (q *Query) ExecQueryCount(ctx context.Context, db DB) int64 {
+	count, err := q.ExecQueryCountErr(ctx, db)
 	if err != nil {
-		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error()+" Query: "+query))
+		panic(ServerError(err))
 	}
 	return count
 }
 
-func (q *Query) ExecQueryMulti(ctx context.Context, db *sql.DB, scanInto any) {
-	query := q.Query()
-	err := sqlscan.Select(ctx, db, scanInto, query)
-	if err != nil {
-		panic(errors.New(errors.UnexpectedStatus, "InternalServerError", err.Error()+" Query: "+query))
+func (q *Query) ExecQueryCountErr(ctx context.Context, db DB) (int64, error) {
+	query, args := q.queryArgs()
+	count := int64(-1)
+	if err := db.QueryRowContext(ctx, query, args...).Scan(&count); err != nil {
+		return count, queryError(err, query)
 	}
+	return count, nil
 }
 
-func (q *Query) ExecQueryMultiErr(ctx context.Context, db *sql.DB, scanInto any) error {
-	query := q.Query()
-	err := sqlscan.Select(ctx, db, scanInto, query)
-	return err
+func (q *Query) ExecQueryMulti(ctx context.Context, db DB, scanInto any) {
+	if err := q.ExecQueryMultiErr(ctx, db, scanInto); err != nil {
+		panic(ServerError(err))
+	}
+}
+
+func (q *Query) ExecQueryMultiErr(ctx context.Context, db DB, scanInto any) error {
+	query, args := q.queryArgs()
+	if err := sqlscan.Select(ctx, db, scanInto, query, args...); err != nil {
+		return queryError(err, query)
+	}
+	if err := afterLoad(ctx, db, scanInto); err != nil {
+		return err
+	}
+	// loaded models get their generator, so updates only write changed columns
+	rows := reflect.Indirect(reflect.ValueOf(scanInto))
+	for i := 0; i < rows.Len(); i++ {
+		row := rows.Index(i)
+		if row.Kind() != reflect.Ptr {
+			row = row.Addr()
+		}
+		if row.Elem().Kind() == reflect.Struct {
+			bindGenerator(row, metadataOf(row.Type()), q.dbType)
+		}
+	}
+	return nil
 }
 
 // Returns a new QueryGenerator
 func NewQueryGenerator(tableName string) QueryGenerator {
 	return &Query{
 		tableName: tableName,
+		dialect:   GetDialect(""),
 	}
 }
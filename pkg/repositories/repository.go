@@ -0,0 +1,345 @@
+package repositories
+
+import (
+	"context"
+	"database/sql"
+	rawErrors "errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+
+	"github.com/georgysavva/scany/v2/sqlscan"
+)
+
+// A column which rows get ordered by
+type Sort struct {
+	Column string
+	Desc   bool
+}
+
+// Options of FindMany
+type FindOptions struct {
+	// Column which rows get ordered by, nothing => database order
+	OrderBy string
+	Desc    bool
+	// Columns which rows get ordered by in order, OrderBy gets ignored if it is set
+	Sort []Sort
+	// Max count of rows, 0 => all
+	Limit  int
+	Offset int
+	// Relations which get loaded on found rows, see Preload
+	Preload []string
+}
+
+// A typed repository of a model, its methods return errors which
+// Classify maps, T is a pointer to the model struct like
+// *models.User and columns are db tags of its fields
+//
+//	users := repositories.NewRepository[*models.User](db, g.MainDatabaseType)
+//	user, err := users.FindOne(ctx, repositories.Eq("phone_number", phone))
+//
+// Models have to embed QueryGenerator, it keeps the snapshot of loaded
+// rows so updates only write changed columns, and it gets set on returned
+// rows so the embedded API keeps working on them
+type Repository[T any] struct {
+	db      DB
+	dbType  string
+	dialect Dialect
+	meta    *metadata
+	// Type of the struct which T points to
+	rowType reflect.Type
+	// Which rows of a soft deleted model it finds
+	scope scope
+}
+
+// Returns a repository of T on `db` which is a `dbType` database
+func NewRepository[T any](db DB, dbType string) *Repository[T] {
+	rowType := reflect.TypeOf((*T)(nil)).Elem()
+	if rowType.Kind() != reflect.Ptr || rowType.Elem().Kind() != reflect.Struct {
+		panic(rawErrors.New("repositories: type of repository is not a pointer to struct"))
+	}
+	meta := metadataOf(rowType)
+	if meta.generator == nil {
+		panic(fmt.Errorf("repositories: `%s` doesn't embed QueryGenerator", rowType.Elem()))
+	}
+	return &Repository[T]{
+		db:      db,
+		dbType:  dbType,
+		dialect: GetDialect(dbType),
+		meta:    meta,
+		rowType: rowType.Elem(),
+	}
+}
+
+// Returns table name of T
+func (r *Repository[T]) Table() string {
+	return r.meta.table
+}
+
+// Returns a new row of T
+func (r *Repository[T]) New() T {
+	row := reflect.New(r.rowType)
+	r.bind(row)
+	return row.Interface().(T)
+}
+
+// Sets the embedded QueryGenerator of a row if it isn't set
+func (r *Repository[T]) bind(row reflect.Value) {
+	bindGenerator(row, r.meta, r.dbType)
+}
+
+// Returns a query generator of `row`
+func (r *Repository[T]) query(row any) *Query {
+	return &Query{tableName: r.meta.table, row: row, dbType: r.dbType, dialect: r.dialect, scope: r.scope}
+}
+
+// Returns a repository which finds deleted rows of a soft deleted model
+// too, only rows which are not deleted get found by default
+func (r *Repository[T]) WithDeleted() *Repository[T] {
+	repository := *r
+	repository.scope = scopeWithDeleted
+	return &repository
+}
+
+// Returns a repository which only finds deleted rows of a soft deleted model
+func (r *Repository[T]) OnlyDeleted() *Repository[T] {
+	repository := *r
+	repository.scope = scopeOnlyDeleted
+	return &repository
+}
+
+// Returns the row which its id is `id`, ErrNotFound if there isn't any
+func (r *Repository[T]) FindByID(ctx context.Context, id any, options ...FindOptions) (T, error) {
+	return r.FindOne(ctx, Eq("id", id), options...)
+}
+
+// Returns the first row which matches `where` (nil => all rows),
+// ErrNotFound if there isn't any
+func (r *Repository[T]) FindOne(ctx context.Context, where Expr, options ...FindOptions) (T, error) {
+	option := FindOptions{}
+	if len(options) > 0 {
+		option = options[0]
+	}
+	option.Limit = 1
+	rows, err := r.FindMany(ctx, where, option)
+	if err != nil {
+		var zero T
+		return zero, err
+	}
+	if len(rows) == 0 {
+		var zero T
+		return zero, Classify(sql.ErrNoRows)
+	}
+	return rows[0], nil
+}
+
+// Returns rows which match `where` (nil => all rows)
+func (r *Repository[T]) FindMany(ctx context.Context, where Expr, options ...FindOptions) ([]T, error) {
+	option := FindOptions{}
+	if len(options) > 0 {
+		option = options[0]
+	}
+	sorts := option.Sort
+	if len(sorts) == 0 && option.OrderBy != "" {
+		sorts = []Sort{{Column: option.OrderBy, Desc: option.Desc}}
+	}
+	orders := make([]string, len(sorts))
+	for i, sort := range sorts {
+		if !r.meta.columnSet[sort.Column] {
+			return nil, fmt.Errorf("repositories: `%s` is not a column of `%s`", sort.Column, r.meta.table)
+		}
+		orders[i] = sort.Column + " ASC"
+		if sort.Desc {
+			orders[i] = sort.Column + " DESC"
+		}
+	}
+
+	q := r.query(reflect.New(r.rowType).Interface())
+	wheres, err := q.exprWheres(where)
+	if err != nil {
+		return nil, err
+	}
+	q.SelectWhere(wheres)
+	if len(orders) > 0 {
+		q.query += " ORDER BY " + strings.Join(orders, ", ")
+	}
+	if option.Limit > 0 {
+		q.query = r.dialect.Paginate(q.query, option.Limit, option.Offset)
+	}
+
+	rows := []T{}
+	query, args := q.queryArgs()
+	if err := sqlscan.Select(ctx, r.db, &rows, query, args...); err != nil {
+		return nil, queryError(err, query)
+	}
+	if err := afterLoad(ctx, r.db, rows); err != nil {
+		return nil, err
+	}
+	for _, row := range rows {
+		r.bind(reflect.ValueOf(row))
+	}
+	if err := r.Preload(ctx, rows, option.Preload...); err != nil {
+		return nil, err
+	}
+	return rows, nil
+}
+
+// Loads `relations` (names of relation fields) of `rows`, one query per
+// relation loads related rows of all of them
+//
+//	tokens, err := models.Tokens(db).FindMany(ctx, where, repositories.FindOptions{Preload: []string{"User"}})
+func (r *Repository[T]) Preload(ctx context.Context, rows []T, relations ...string) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	for _, relation := range relations {
+		if err := preload(ctx, r.db, r.dbType, reflect.ValueOf(rows), relation); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Returns count of rows which match `where` (nil => all rows)
+func (r *Repository[T]) Count(ctx context.Context, where Expr) (int64, error) {
+	q := r.query(reflect.New(r.rowType).Interface())
+	wheres, err := q.exprWheres(where)
+	if err != nil {
+		return 0, err
+	}
+	q.query = fmt.Sprintf("SELECT COUNT(*) as count FROM %s", r.meta.table)
+	if wheres = q.scoped(wheres, ""); wheres != "" {
+		q.query += " WHERE " + wheres
+	}
+	return q.ExecQueryCountErr(ctx, r.db)
+}
+
+// Reports if any row matches `where` (nil => all rows)
+func (r *Repository[T]) Exists(ctx context.Context, where Expr) (bool, error) {
+	count, err := r.Count(ctx, where)
+	return count > 0, err
+}
+
+// Inserts `row` and fills its generated columns like id
+func (r *Repository[T]) Create(ctx context.Context, row T) error {
+	q := r.query(row)
+	q.InsertInto()
+	_, err := q.ExecQueryErr(ctx, r.db)
+	r.bind(reflect.ValueOf(row))
+	return err
+}
+
+// Inserts `row` or updates `updateColumns` of the existing row which
+// conflicts on `conflictColumns`, then the existing row gets loaded into
+// `row`, see QueryGenerator.Upsert for the unique keys which mysql uses
+func (r *Repository[T]) Upsert(ctx context.Context, row T, conflictColumns []string, updateColumns []string) error {
+	if len(conflictColumns) == 0 {
+		return fmt.Errorf("repositories: upsert of `%s` has no conflict column", r.meta.table)
+	}
+	for _, column := range append(append([]string{}, conflictColumns...), updateColumns...) {
+		if !r.meta.columnSet[column] {
+			return fmt.Errorf("repositories: `%s` is not a column of `%s`", column, r.meta.table)
+		}
+	}
+	q := r.query(row)
+	q.Upsert(conflictColumns, updateColumns)
+	_, err := q.ExecQueryErr(ctx, r.db)
+	r.bind(reflect.ValueOf(row))
+	return err
+}
+
+// Updates columns of `row` by its id, only changed ones if the row got
+// loaded by the repository, nothing gets executed if nothing changed
+func (r *Repository[T]) Update(ctx context.Context, row T) error {
+	q := r.query(row)
+	q.UpdateMe()
+	_, err := q.ExecQueryErr(ctx, r.db)
+	return err
+}
+
+// Updates only `columns` of `row` by its id
+func (r *Repository[T]) UpdateFields(ctx context.Context, row T, columns ...string) error {
+	for _, column := range columns {
+		if !r.meta.columnSet[column] {
+			return fmt.Errorf("repositories: `%s` is not a column of `%s`", column, r.meta.table)
+		}
+	}
+	q := r.query(row)
+	q.UpdateFields(columns...)
+	_, err := q.ExecQueryErr(ctx, r.db)
+	return err
+}
+
+// Deletes `row` by its id, rows of soft deleted models get marked as deleted
+func (r *Repository[T]) Delete(ctx context.Context, row T) error {
+	q := r.query(row)
+	q.DeleteMe()
+	_, err := q.ExecQueryErr(ctx, r.db)
+	return err
+}
+
+// Deletes `row` by its id for real, even if the model is soft deleted
+func (r *Repository[T]) ForceDelete(ctx context.Context, row T) error {
+	q := r.query(row)
+	q.ForceDelete()
+	_, err := q.ExecQueryErr(ctx, r.db)
+	return err
+}
+
+// Undeletes `row` of a soft deleted model by its id
+func (r *Repository[T]) Restore(ctx context.Context, row T) error {
+	if r.meta.softDelete == "" {
+		return fmt.Errorf("repositories: rows of `%s` are not soft deleted", r.meta.table)
+	}
+	q := r.query(row)
+	q.Restore()
+	_, err := q.ExecQueryErr(ctx, r.db)
+	return err
+}
+
+// Deletes rows of a soft deleted model for real which got deleted before
+// `before` and returns their count
+func (r *Repository[T]) Purge(ctx context.Context, before time.Time) (int64, error) {
+	if r.meta.softDelete == "" {
+		return 0, fmt.Errorf("repositories: rows of `%s` are not soft deleted", r.meta.table)
+	}
+	q := r.query(reflect.New(r.rowType).Interface())
+	wheres, err := q.exprWheres(Lt(r.meta.softDelete, before))
+	if err != nil {
+		return 0, err
+	}
+	return r.exec(ctx, fmt.Sprintf("DELETE FROM %s WHERE %s", r.meta.table, wheres), q.args)
+}
+
+// Sets columns of rows which match `where` (nil => all rows) to values of
+// `set` and returns their count, hooks don't get called for them
+//
+//	count, err := users.UpdateWhere(ctx, repositories.Eq("is_active", false), map[string]any{"is_admin": false})
+func (r *Repository[T]) UpdateWhere(ctx context.Context, where Expr, set map[string]any) (int64, error) {
+	q := r.query(reflect.New(r.rowType).Interface())
+	if err := q.updateWhere(where, set); err != nil {
+		return 0, err
+	}
+	return q.ExecQueryErr(ctx, r.db)
+}
+
+// Deletes rows which match `where` (nil => all rows) and returns their
+// count, rows of soft deleted models get marked as deleted, hooks don't
+// get called for them
+func (r *Repository[T]) DeleteWhere(ctx context.Context, where Expr) (int64, error) {
+	q := r.query(reflect.New(r.rowType).Interface())
+	if err := q.deleteWhere(where); err != nil {
+		return 0, err
+	}
+	return q.ExecQueryErr(ctx, r.db)
+}
+
+// Executes `query` and returns count of affected rows
+func (r *Repository[T]) exec(ctx context.Context, query string, args []any) (int64, error) {
+	result, err := r.db.ExecContext(ctx, query, args...)
+	if err != nil {
+		return 0, queryError(err, query)
+	}
+	return result.RowsAffected()
+}
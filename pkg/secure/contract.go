@@ -0,0 +1,10 @@
+package secure
+
+type (
+	Option struct {
+		CertFile, KeyFile, MinVersion, ClientCAFile, ClientAuth string
+		CipherSuites                                            []string
+		// Based on Seconds, 0 => certificates never get reloaded
+		ReloadInterval int64
+	}
+)
@@ -0,0 +1,200 @@
+package secure
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	minVersions = map[string]uint16{
+		"":    tls.VersionTLS12,
+		"1.0": tls.VersionTLS10,
+		"1.1": tls.VersionTLS11,
+		"1.2": tls.VersionTLS12,
+		"1.3": tls.VersionTLS13,
+	}
+
+	clientAuths = map[string]tls.ClientAuthType{
+		"":                   tls.NoClientCert,
+		"none":               tls.NoClientCert,
+		"request":            tls.RequestClientCert,
+		"require":            tls.RequireAnyClientCert,
+		"verify_if_given":    tls.VerifyClientCertIfGiven,
+		"require_and_verify": tls.RequireAndVerifyClientCert,
+	}
+)
+
+// Reports if passed tls min_version is supported
+func IsValidMinVersion(version string) bool {
+	_, ok := minVersions[version]
+	return ok
+}
+
+// Reports if passed client_auth is supported
+func IsValidClientAuth(clientAuth string) bool {
+	_, ok := clientAuths[strings.ToLower(clientAuth)]
+	return ok
+}
+
+// Keeps latest loaded certificate and client CA pool, so that
+// they can get replaced while server is running
+type certificates struct {
+	opt *Option
+
+	cert     atomic.Pointer[tls.Certificate]
+	clientCA atomic.Pointer[x509.CertPool]
+
+	modified time.Time
+}
+
+// Takes options needed for tls configs and returns a *tls.Config
+// with HTTP/2 enabled
+//
+// If `opt.ReloadInterval` is bigger than 0, certificate files get
+// checked every `opt.ReloadInterval` seconds and get reloaded on change,
+// errors of reloading get passed to `onError` and old certificates stay
+func New(opt *Option, onError func(error)) (*tls.Config, error) {
+	if opt == nil {
+		return nil, errors.New("option can not be nil")
+	}
+
+	minVersion, ok := minVersions[opt.MinVersion]
+	if !ok {
+		return nil, fmt.Errorf("secure: unknown tls min_version `%s`", opt.MinVersion)
+	}
+	clientAuth, ok := clientAuths[strings.ToLower(opt.ClientAuth)]
+	if !ok {
+		return nil, fmt.Errorf("secure: unknown tls client_auth `%s`", opt.ClientAuth)
+	}
+	cipherSuites, err := ParseCipherSuites(opt.CipherSuites)
+	if err != nil {
+		return nil, err
+	}
+
+	c := &certificates{opt: opt}
+	if err := c.load(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion:     minVersion,
+		CipherSuites:   cipherSuites,
+		ClientAuth:     clientAuth,
+		NextProtos:     []string{"h2", "http/1.1"},
+		GetCertificate: c.getCertificate,
+	}
+	if opt.ClientCAFile != "" {
+		config.ClientCAs = c.clientCA.Load()
+		config.GetConfigForClient = func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			clientConfig := config.Clone()
+			clientConfig.GetConfigForClient = nil
+			clientConfig.ClientCAs = c.clientCA.Load()
+			return clientConfig, nil
+		}
+	}
+
+	if opt.ReloadInterval > 0 {
+		go c.watch(time.Duration(opt.ReloadInterval)*time.Second, onError)
+	}
+
+	return config, nil
+}
+
+// Converts cipher suite names (like TLS_ECDHE_RSA_WITH_AES_128_GCM_SHA256)
+// to their ids
+//
+// Empty names means go defaults
+func ParseCipherSuites(names []string) ([]uint16, error) {
+	if len(names) == 0 {
+		return nil, nil
+	}
+
+	known := map[string]uint16{}
+	for _, suite := range append(tls.CipherSuites(), tls.InsecureCipherSuites()...) {
+		known[suite.Name] = suite.ID
+	}
+
+	ids := make([]uint16, 0, len(names))
+	for _, name := range names {
+		id, ok := known[strings.TrimSpace(name)]
+		if !ok {
+			return nil, fmt.Errorf("secure: unknown tls cipher suite `%s`", name)
+		}
+		ids = append(ids, id)
+	}
+	return ids, nil
+}
+
+// Returns the latest modification time between certificate files
+func (c *certificates) lastModified() (time.Time, error) {
+	latest := time.Time{}
+	for _, path := range []string{c.opt.CertFile, c.opt.KeyFile, c.opt.ClientCAFile} {
+		if path == "" {
+			continue
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return latest, err
+		}
+		if info.ModTime().After(latest) {
+			latest = info.ModTime()
+		}
+	}
+	return latest, nil
+}
+
+// Loads certificate files and replaces the current ones
+func (c *certificates) load() error {
+	modified, err := c.lastModified()
+	if err != nil {
+		return err
+	}
+
+	cert, err := tls.LoadX509KeyPair(c.opt.CertFile, c.opt.KeyFile)
+	if err != nil {
+		return err
+	}
+
+	var pool *x509.CertPool = nil
+	if c.opt.ClientCAFile != "" {
+		bundle, err := os.ReadFile(c.opt.ClientCAFile)
+		if err != nil {
+			return err
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(bundle) {
+			return fmt.Errorf("secure: no certificate found in `%s`", c.opt.ClientCAFile)
+		}
+	}
+
+	c.cert.Store(&cert)
+	c.clientCA.Store(pool)
+	c.modified = modified
+	return nil
+}
+
+// Reloads certificates whenever one of the files changes
+func (c *certificates) watch(interval time.Duration, onError func(error)) {
+	for range time.Tick(interval) {
+		modified, err := c.lastModified()
+		if err == nil && !modified.After(c.modified) {
+			continue
+		}
+		if err == nil {
+			err = c.load()
+		}
+		if err != nil && onError != nil {
+			onError(err)
+		}
+	}
+}
+
+func (c *certificates) getCertificate(*tls.ClientHelloInfo) (*tls.Certificate, error) {
+	return c.cert.Load(), nil
+}
@@ -0,0 +1,256 @@
+package seeds
+
+import (
+	"context"
+	"crypto/sha256"
+	"database/sql"
+	"encoding/hex"
+	"encoding/json"
+	rawErrors "errors"
+	"fmt"
+	"io/fs"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"time"
+
+	"service/pkg/repositories"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Table which keeps checksum of seeded files, so unchanged files get skipped
+const ChecksumTable = "seed_checksums"
+
+type (
+	// Content of a seed file
+	//
+	//	table: users
+	//	key: [phone_number]
+	//	rows:
+	//	  - phone_number: "+989120000000"
+	//	    display_name: "Admin"
+	//	    password: "admin"
+	File struct {
+		// Table of the rows, a model has to be registered for it
+		Table string `yaml:"table" json:"table"`
+		// Columns which identify a row, rows which already exist get updated
+		Key []string `yaml:"key" json:"key"`
+		// Rows by their column name
+		Rows []map[string]any `yaml:"rows" json:"rows"`
+	}
+
+	// Returns a new model of a table
+	ModelFunc func() repositories.QueryGenerator
+)
+
+// Seed files (.yaml, .yml and .json) of an environment which get
+// upserted in order of their names
+type Seeds struct {
+	fsys   fs.FS
+	models map[string]ModelFunc
+}
+
+// Returns seeds which are in `root/<env>` folder of `fsys`, no seed
+// exists for env if the folder doesn't exist
+func New(fsys fs.FS, root string, env string) (*Seeds, error) {
+	sub, err := fs.Sub(fsys, root+"/"+env)
+	if err != nil {
+		return nil, err
+	}
+	return &Seeds{fsys: sub, models: map[string]ModelFunc{}}, nil
+}
+
+// Registers the model of a table, rows of the table get saved by it
+func (s *Seeds) Register(table string, model ModelFunc) {
+	s.models[table] = model
+}
+
+// Upserts rows of seed files and returns count of upserted rows, files
+// which didn't change since the last run get skipped unless `force` is true
+func (s *Seeds) Run(ctx context.Context, db *sql.DB, force bool) (int, error) {
+	entries, err := fs.ReadDir(s.fsys, ".")
+	if err != nil {
+		if rawErrors.Is(err, fs.ErrNotExist) {
+			return 0, nil
+		}
+		return 0, err
+	}
+	stored, err := storedChecksums(ctx, db)
+	if err != nil {
+		return 0, err
+	}
+
+	count := 0
+	for _, entry := range entries {
+		switch filepath.Ext(entry.Name()) {
+		case ".yaml", ".yml", ".json":
+		default:
+			continue
+		}
+		content, err := fs.ReadFile(s.fsys, entry.Name())
+		if err != nil {
+			return count, err
+		}
+		sum := sha256.Sum256(content)
+		checksum := hex.EncodeToString(sum[:])
+		if previous, ok := stored[entry.Name()]; ok && previous == checksum && !force {
+			continue
+		}
+
+		file := File{}
+		if filepath.Ext(entry.Name()) == ".json" {
+			err = json.Unmarshal(content, &file)
+		} else {
+			err = yaml.Unmarshal(content, &file)
+		}
+		if err != nil {
+			return count, fmt.Errorf("seeds: %s: %w", entry.Name(), err)
+		}
+
+		n, err := s.seed(ctx, db, file)
+		count += n
+		if err != nil {
+			return count, fmt.Errorf("seeds: %s: %w", entry.Name(), err)
+		}
+		if err := storeChecksum(ctx, db, entry.Name(), checksum, stored); err != nil {
+			return count, err
+		}
+	}
+	return count, nil
+}
+
+// Upserts rows of a seed file
+func (s *Seeds) seed(ctx context.Context, db *sql.DB, file File) (int, error) {
+	newModel, ok := s.models[file.Table]
+	if !ok {
+		return 0, fmt.Errorf("no model is registered for `%s` table", file.Table)
+	}
+	if len(file.Key) == 0 {
+		return 0, fmt.Errorf("key of `%s` table is empty", file.Table)
+	}
+
+	for i, row := range file.Rows {
+		where := map[string]any{}
+		for _, column := range file.Key {
+			value, ok := row[column]
+			if !ok {
+				return i, fmt.Errorf("row %d: key column `%s` is missing", i, column)
+			}
+			where[column] = value
+		}
+
+		model := newModel()
+		exists := true
+		if err := model.Select(where).ExecQueryRowErr(ctx, db); err != nil {
+			if !rawErrors.Is(err, sql.ErrNoRows) {
+				return i, err
+			}
+			exists = false
+		}
+
+		for column, value := range row {
+			if err := setColumn(model, column, value); err != nil {
+				return i, fmt.Errorf("row %d: %w", i, err)
+			}
+		}
+
+		if err := save(ctx, db, model, exists); err != nil {
+			return i, fmt.Errorf("row %d: %w", i, err)
+		}
+	}
+	return len(file.Rows), nil
+}
+
+// Updates the row if it exists, otherwise inserts it, hooks of the
+// model like hashing passwords run on both
+func save(ctx context.Context, db *sql.DB, model repositories.QueryGenerator, exists bool) error {
+	if exists {
+		_, err := model.UpdateMe().ExecQueryErr(ctx, db)
+		return err
+	}
+	_, err := model.InsertInto().ExecQueryErr(ctx, db)
+	return err
+}
+
+// Sets field of `model` which its db tag is `column`
+func setColumn(model any, column string, value any) error {
+	row := reflect.ValueOf(model)
+	for row.Kind() == reflect.Ptr || row.Kind() == reflect.Interface {
+		row = row.Elem()
+	}
+	for _, f := range reflect.VisibleFields(row.Type()) {
+		if !f.IsExported() || f.Tag.Get("db") != column {
+			continue
+		}
+		field := row.FieldByIndex(f.Index)
+		if value == nil {
+			field.Set(reflect.Zero(field.Type()))
+			return nil
+		}
+		return assign(field, reflect.ValueOf(value), column)
+	}
+	return fmt.Errorf("`%s` is not a column of `%s`", column, row.Type())
+}
+
+func assign(field reflect.Value, value reflect.Value, column string) error {
+	if field.Kind() == reflect.Ptr {
+		elem := reflect.New(field.Type().Elem())
+		if err := assign(elem.Elem(), value, column); err != nil {
+			return err
+		}
+		field.Set(elem)
+		return nil
+	}
+	if field.Type() == reflect.TypeOf(time.Time{}) && value.Kind() == reflect.String {
+		parsed, err := time.Parse(time.RFC3339, value.String())
+		if err != nil {
+			return fmt.Errorf("`%s` has to be like %s", column, time.RFC3339)
+		}
+		value = reflect.ValueOf(parsed)
+	}
+	if !value.Type().ConvertibleTo(field.Type()) || (value.Kind() == reflect.String) != (field.Kind() == reflect.String) {
+		return fmt.Errorf("`%s` can't be %v", column, value.Interface())
+	}
+	field.Set(value.Convert(field.Type()))
+	return nil
+}
+
+// Creates checksum table if it doesn't exist and returns stored checksums
+func storedChecksums(ctx context.Context, db *sql.DB) (map[string]string, error) {
+	if _, err := db.ExecContext(ctx, fmt.Sprintf("SELECT COUNT(*) FROM %s", ChecksumTable)); err != nil {
+		create := fmt.Sprintf("CREATE TABLE %s (name VARCHAR(255) NOT NULL PRIMARY KEY, checksum VARCHAR(64) NOT NULL)", ChecksumTable)
+		if _, err := db.ExecContext(ctx, create); err != nil {
+			return nil, err
+		}
+	}
+
+	rows, err := db.QueryContext(ctx, fmt.Sprintf("SELECT name, checksum FROM %s", ChecksumTable))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	stored := map[string]string{}
+	for rows.Next() {
+		var name, checksum string
+		if err := rows.Scan(&name, &checksum); err != nil {
+			return nil, err
+		}
+		stored[name] = checksum
+	}
+	return stored, rows.Err()
+}
+
+func storeChecksum(ctx context.Context, db *sql.DB, name string, checksum string, stored map[string]string) error {
+	query := fmt.Sprintf("INSERT INTO %s (name, checksum) VALUES (%s, %s)", ChecksumTable, quote(name), quote(checksum))
+	if _, ok := stored[name]; ok {
+		query = fmt.Sprintf("UPDATE %s SET checksum = %s WHERE name = %s", ChecksumTable, quote(checksum), quote(name))
+	}
+	_, err := db.ExecContext(ctx, query)
+	return err
+}
+
+func quote(value string) string {
+	return "'" + strings.ReplaceAll(value, "'", "''") + "'"
+}
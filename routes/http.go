@@ -1,6 +1,8 @@
 package routes
 
 import (
+	"net/http"
+	"service/config"
 	"service/dto"
 	g "service/global"
 	"service/handlers"
@@ -8,24 +10,53 @@ import (
 	"service/middlewares"
 	"service/middlewares/extra_middlewares"
 	"strings"
+	"sync/atomic"
 	"time"
 
 	"github.com/kataras/iris/v12"
 	"github.com/rs/cors"
 )
 
+func newCors(cfg *config.Config) *cors.Cors {
+	return cors.New(cors.Options{
+		AllowedOrigins:   strings.Split(cfg.AllowOrigins, ","),
+		AllowedHeaders:   strings.Split(cfg.AllowHeaders, ","),
+		MaxAge:           cfg.MaxAge,
+		AllowCredentials: true,
+	})
+}
+
+// Applies reloaded configs to the middlewares
+func reloadMiddlewares(c *atomic.Pointer[cors.Cors]) func(old, new *config.Config) {
+	return func(old, new *config.Config) {
+		if old.AllowOrigins != new.AllowOrigins || old.AllowHeaders != new.AllowHeaders || old.MaxAge != new.MaxAge {
+			c.Store(newCors(new))
+		}
+		if old.Timeout != new.Timeout {
+			extra_middlewares.SetTimeout(time.Second * time.Duration(new.Timeout))
+		}
+		if old.MaxConcurrentRequests != new.MaxConcurrentRequests {
+			extra_middlewares.SetMaxConcurrentRequests(new.MaxConcurrentRequests)
+		}
+	}
+}
+
 // Applies all necessary middlewares
 func addMiddlewares(app *iris.Application) {
 	// Copression
 	app.UseRouter(iris.Compression)
 
+	// HSTS
+	if tls := g.CFG().Gateway.TLS; tls.Enabled && tls.HSTSMaxAge > 0 {
+		app.UseRouter(extra_middlewares.HSTS(tls.HSTSMaxAge, tls.HSTSIncludeSubdomains, tls.HSTSPreload))
+	}
+
 	// Cors
-	c := cors.New(cors.Options{
-		AllowedOrigins:   strings.Split(g.CFG.AllowOrigins, ","),
-		AllowedHeaders:   strings.Split(g.CFG.AllowHeaders, ","),
-		AllowCredentials: true,
+	c := &atomic.Pointer[cors.Cors]{}
+	c.Store(newCors(g.CFG()))
+	app.WrapRouter(func(w http.ResponseWriter, r *http.Request, next http.HandlerFunc) {
+		c.Load().ServeHTTP(w, r, next)
 	})
-	app.WrapRouter(c.ServeHTTP)
 
 	// Translator
 	app.Use(extra_middlewares.Translator)
@@ -34,13 +65,15 @@ func addMiddlewares(app *iris.Application) {
 	app.Use(extra_middlewares.Panic)
 
 	// Timeout
-	app.Use(extra_middlewares.Timeout(time.Second * time.Duration(g.CFG.Timeout)))
+	app.Use(extra_middlewares.Timeout(time.Second * time.Duration(g.CFG().Timeout)))
 
 	// RateLimiter
-	app.Use(extra_middlewares.ConcurrentLimiter(g.CFG.MaxConcurrentRequests))
+	app.Use(extra_middlewares.ConcurrentLimiter(g.CFG().MaxConcurrentRequests))
 
 	// Creates a db for every db operation
 	app.Use(extra_middlewares.CreateDbInstance)
+
+	g.Configs.Subscribe(reloadMiddlewares(c))
 }
 
 func HTTP(app *iris.Application) {
@@ -62,7 +95,15 @@ func HTTP(app *iris.Application) {
 		apiParty := app.Party("/api", middlewares.Auth)
 
 		apiParty.Get("/me", handlers.Me)
+	}
+
+	{ // /api admin party, only served on the admin listener if it is set
+		guards := []iris.Handler{}
+		if admin := g.CFG().Gateway.Admin; admin != "" {
+			guards = append(guards, middlewares.OnlyListeners(admin))
+		}
+		adminParty := app.Party("/api", append(guards, middlewares.Auth)...)
 
-		apiParty.Get("/users", handlers.Users)
+		adminParty.Get("/users", handlers.Users)
 	}
 }
@@ -90,6 +90,35 @@ func SendPage(ctx iris.Context, dataCount int64, perPage int, page int, data any
 	})
 }
 
+// Sends a page of cursor pagination, `next` and `prev` are cursors of the
+// neighbour pages which get sent as links of the current url, empty => null
+func SendCursorPage(ctx iris.Context, perPage int, next string, prev string, data any) {
+	dataValue := reflect.ValueOf(data)
+	if dataValue.Type().Kind() == reflect.Ptr {
+		dataValue = dataValue.Elem()
+	}
+
+	sendIfCtxNotCancelled(ctx, -1, map[string]any{
+		"per_page": perPage,
+		"count":    dataValue.Len(),
+		"next":     cursorLink(ctx, next),
+		"prev":     cursorLink(ctx, prev),
+		"data":     data,
+	})
+}
+
+// Returns current url which its cursor parameter is `cursor`, nil if cursor is empty
+func cursorLink(ctx iris.Context, cursor string) any {
+	if cursor == "" {
+		return nil
+	}
+	link := *ctx.Request().URL
+	query := link.Query()
+	query.Set("cursor", cursor)
+	link.RawQuery = query.Encode()
+	return link.RequestURI()
+}
+
 func CalculatePagesCount(dataCount int64, perPage int) int {
 	pagesCount := int64(-1)
 	if dataCount%int64(perPage) == 0 {